@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	jaeger "github.com/jaegertracing/jaeger/model"
+
+	"otlp-converter-go/pkg/otlpconvert"
+)
+
+// benchmarkTemplates is the number of distinct synthetic spans cycled through
+// by -benchmark. They are encoded once up front so the timed loop measures
+// only decoding and conversion.
+const benchmarkTemplates = 1024
+
+// runBenchmark pushes n synthetic entries through the normal worker pipeline,
+// returns the converted spans to the pool as writeOutput does once a batch is
+// written, and reports conversion throughput. No files are read or written.
+func runBenchmark(config *otlpconvert.Config, n int) {
+	entries := make([]otlpconvert.BadgerEntry, benchmarkTemplates)
+	for i := range entries {
+		data, err := proto.Marshal(syntheticSpan(i))
+		if err != nil {
+			fatal("failed to encode synthetic span", "error", err)
+		}
+		entries[i] = otlpconvert.BadgerEntry{
+			Key:   fmt.Sprintf("bench-%d", i),
+			Value: encodeEntryValue(data, config.ValueEncoding),
+		}
+	}
+
+	// Nothing is written, so there is no checkpoint to track
+	config.CheckpointFile = ""
+	converter := otlpconvert.New(*config)
+	entryChan := make(chan otlpconvert.BadgerEntry, config.EntryQueue)
+	resultChan := make(chan *otlpconvert.OTLPSpan, config.ResultQueue)
+
+	slog.Info("running benchmark", "spans", n, "workers", config.NumWorkers)
+	startTime := time.Now()
+
+	var wg sync.WaitGroup
+	for i := 0; i < config.NumWorkers; i++ {
+		wg.Add(1)
+		go converter.Worker(entryChan, resultChan, &wg)
+	}
+
+	converted := 0
+	drained := make(chan struct{})
+	go func() {
+		for span := range resultChan {
+			converted++
+			otlpconvert.ReleaseSpan(span)
+		}
+		close(drained)
+	}()
+
+	for i := 0; i < n; i++ {
+		entryChan <- entries[i%len(entries)]
+	}
+	close(entryChan)
+	wg.Wait()
+	close(resultChan)
+	<-drained
+
+	elapsed := time.Since(startTime)
+	rate := float64(converted) / elapsed.Seconds()
+	slog.Info("benchmark complete",
+		"spans", converted,
+		"parse_errors", converter.ParseErrors(),
+		"workers", config.NumWorkers,
+		"elapsed", elapsed.Round(time.Millisecond).String(),
+		"spans_per_sec", int64(rate),
+	)
+	if config.ProfileParse {
+		slog.Info("parse latency",
+			"p50", converter.ParseLatency(50).String(),
+			"p95", converter.ParseLatency(95).String(),
+			"p99", converter.ParseLatency(99).String(),
+		)
+	}
+	if config.LogFormat == "text" {
+		fmt.Printf("Converted %d spans in %.2fs with %d workers: %.0f spans/sec\n", converted, elapsed.Seconds(), config.NumWorkers, rate)
+	}
+}
+
+// syntheticSpan builds a representative server span with tags, a log and a
+// parent reference. i varies the IDs and timestamps.
+func syntheticSpan(i int) *jaeger.Span {
+	traceID := jaeger.NewTraceID(uint64(i/8+1), uint64(i+1))
+	start := time.Unix(1700000000, 0).Add(time.Duration(i) * time.Millisecond)
+	return &jaeger.Span{
+		TraceID:       traceID,
+		SpanID:        jaeger.NewSpanID(uint64(i + 1)),
+		OperationName: fmt.Sprintf("GET /api/resource/%d", i%16),
+		StartTime:     start,
+		Duration:      time.Duration(i%100+1) * time.Millisecond,
+		Flags:         jaeger.Flags(1),
+		References: []jaeger.SpanRef{
+			{TraceID: traceID, SpanID: jaeger.NewSpanID(uint64(i/8*8 + 1)), RefType: jaeger.SpanRefType_CHILD_OF},
+		},
+		Tags: []jaeger.KeyValue{
+			jaeger.String("span.kind", "server"),
+			jaeger.String("http.method", "GET"),
+			jaeger.String("http.url", fmt.Sprintf("https://example.com/api/resource/%d", i%16)),
+			jaeger.Int64("http.status_code", 200),
+			jaeger.Bool("error", false),
+		},
+		Logs: []jaeger.Log{
+			{Timestamp: start.Add(time.Millisecond), Fields: []jaeger.KeyValue{jaeger.String("event", "handled")}},
+		},
+		Process: &jaeger.Process{
+			ServiceName: fmt.Sprintf("service-%d", i%4),
+			Tags: []jaeger.KeyValue{
+				jaeger.String("hostname", fmt.Sprintf("host-%d", i%8)),
+				jaeger.String("jaeger.version", "Go-2.30.0"),
+			},
+		},
+	}
+}
+
+// encodeEntryValue encodes protobuf bytes the way an export entry holds them
+// for the given -value-encoding
+func encodeEntryValue(data []byte, encoding string) otlpconvert.EntryValue {
+	switch encoding {
+	case "base64":
+		return otlpconvert.EntryValue(base64.StdEncoding.EncodeToString(data))
+	case "raw":
+		return otlpconvert.EntryValue(data)
+	default: // "hex"
+		return otlpconvert.EntryValue(hex.EncodeToString(data))
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// followPollInterval is how often -follow checks the input for new bytes
+const followPollInterval = 500 * time.Millisecond
+
+// followReader reads a file like tail -f: at end of file it waits for more
+// bytes instead of returning io.EOF, until stop is closed. A partly written
+// entry therefore blocks the decoder rather than failing it.
+type followReader struct {
+	file *os.File
+	stop <-chan struct{}
+}
+
+func (r *followReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.file.Read(p)
+		if n > 0 || err != io.EOF {
+			return n, err
+		}
+		select {
+		case <-r.stop:
+			return 0, io.EOF
+		case <-time.After(followPollInterval):
+		}
+	}
+}
+
+// followStop returns a channel closed on the first SIGINT or SIGTERM, which
+// ends -follow so the run drains and finishes normally. A second signal
+// kills the process as usual.
+func followStop() <-chan struct{} {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx.Done()
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	jaeger "github.com/jaegertracing/jaeger/model"
+
+	"otlp-converter-go/pkg/otlpconvert"
+)
+
+// generateStart is the start time of the first generated trace. It is fixed
+// so that a seed always gives the same timestamps.
+var generateStart = time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC)
+
+// generateServices are the services of generated traces, each with the
+// operations it serves
+var generateServices = []struct {
+	name       string
+	operations []string
+}{
+	{"frontend", []string{"GET /", "GET /cart", "POST /checkout", "GET /product/{id}"}},
+	{"checkout", []string{"PlaceOrder", "PrepareOrder"}},
+	{"payment", []string{"Charge", "Refund"}},
+	{"inventory", []string{"Reserve", "GetStock"}},
+	{"users", []string{"GetUser", "Authenticate"}},
+	{"postgres", []string{"SELECT orders", "INSERT orders", "UPDATE stock"}},
+	{"kafka", []string{"orders publish", "orders process"}},
+}
+
+// generateKinds are the span.kind tags given to child spans; roots are always
+// servers
+var generateKinds = []string{"server", "client", "producer", "consumer", "internal"}
+
+// generateEntries queues n synthetic Jaeger spans into entryChan, encoded
+// with -value-encoding as if read from an export, and returns how many were
+// queued. Spans come in traces of one to eight spans across the services of
+// generateServices, with varied kinds, some errors and some log events. The
+// same seed always gives the same spans, so a rerun (or -resume) sees the same
+// input.
+func generateEntries(n int, seed int64, entryChan chan<- otlpconvert.BadgerEntry, config *otlpconvert.Config, done <-chan struct{}) int {
+	slog.Info("generating spans", "spans", n, "seed", seed, "workers", config.NumWorkers, "batch_size", config.BatchSize)
+	rng := rand.New(rand.NewSource(seed))
+	var processed atomic.Int64
+
+	start := generateStart
+	for i, trace := 0, 0; i < n; trace++ {
+		spans := generateTrace(rng, trace, start, min(1+rng.Intn(8), n-i))
+		for _, span := range spans {
+			data, err := proto.Marshal(span)
+			if err != nil {
+				fatal("failed to encode generated span", "error", err)
+			}
+			entry := otlpconvert.BadgerEntry{
+				Key:   fmt.Sprintf("gen-%d", i),
+				Value: encodeEntryValue(data, config.ValueEncoding),
+			}
+			i++
+			if !queueEntry(entry, entryChan, &processed, config, done) {
+				return int(processed.Load())
+			}
+		}
+		start = start.Add(time.Duration(1+rng.Intn(500)) * time.Millisecond)
+	}
+	return int(processed.Load())
+}
+
+// generateTrace builds a trace of size spans: a server root and children that
+// each hang off an earlier span of the trace and fit within its duration
+func generateTrace(rng *rand.Rand, trace int, start time.Time, size int) []*jaeger.Span {
+	traceID := jaeger.NewTraceID(rng.Uint64(), rng.Uint64()|1) // never zero
+	spans := make([]*jaeger.Span, 0, size)
+	for i := 0; i < size; i++ {
+		// Roots are frontend requests
+		service := generateServices[0]
+		kind := "server"
+		spanStart := start
+		duration := time.Duration(20+rng.Intn(980)) * time.Millisecond
+		var refs []jaeger.SpanRef
+		if i > 0 {
+			service = generateServices[rng.Intn(len(generateServices))]
+			parent := spans[rng.Intn(len(spans))]
+			kind = generateKinds[rng.Intn(len(generateKinds))]
+			offset := time.Duration(rng.Int63n(int64(parent.Duration)/2 + 1))
+			spanStart = parent.StartTime.Add(offset)
+			duration = time.Duration(rng.Int63n(int64(parent.Duration-offset)) + 1)
+			refs = []jaeger.SpanRef{{TraceID: traceID, SpanID: parent.SpanID, RefType: jaeger.SpanRefType_CHILD_OF}}
+		}
+
+		failed := rng.Intn(20) == 0
+		status := int64(200)
+		if failed {
+			status = 500
+		}
+		tags := []jaeger.KeyValue{
+			jaeger.String("span.kind", kind),
+			jaeger.Int64("http.status_code", status),
+			jaeger.Bool("error", failed),
+		}
+
+		var logs []jaeger.Log
+		if failed {
+			logs = append(logs, jaeger.Log{
+				Timestamp: spanStart.Add(duration / 2),
+				Fields: []jaeger.KeyValue{
+					jaeger.String("event", "error"),
+					jaeger.String("message", fmt.Sprintf("%s failed", service.name)),
+				},
+			})
+		}
+		for j := rng.Intn(3); j > 0; j-- {
+			logs = append(logs, jaeger.Log{
+				Timestamp: spanStart.Add(time.Duration(rng.Int63n(int64(duration)))),
+				Fields:    []jaeger.KeyValue{jaeger.String("event", "checkpoint"), jaeger.Int64("step", int64(j))},
+			})
+		}
+
+		spans = append(spans, &jaeger.Span{
+			TraceID:       traceID,
+			SpanID:        jaeger.NewSpanID(rng.Uint64() | 1), // never zero
+			OperationName: service.operations[rng.Intn(len(service.operations))],
+			StartTime:     spanStart,
+			Duration:      duration,
+			Flags:         jaeger.Flags(1),
+			References:    refs,
+			Tags:          tags,
+			Logs:          logs,
+			Process: &jaeger.Process{
+				ServiceName: service.name,
+				Tags: []jaeger.KeyValue{
+					jaeger.String("hostname", fmt.Sprintf("%s-%d", service.name, trace%3)),
+					jaeger.String("jaeger.version", "Go-2.30.0"),
+				},
+			},
+		})
+	}
+	return spans
+}
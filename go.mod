@@ -1,6 +1,6 @@
 module otlp-converter-go
 
-go 1.19
+go 1.21
 
 require (
 	github.com/apache/arrow/go/v14 v14.0.2
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"otlp-converter-go/pkg/otlpconvert"
+)
+
+// readBadgerExport streams entries from a BadgerDB export ({"entries":[...]})
+// into entryChan, adding them to processed. It returns false once the
+// configured entry limit is reached or done is closed.
+func readBadgerExport(r io.Reader, entryChan chan<- otlpconvert.BadgerEntry, processed *atomic.Int64, config *otlpconvert.Config, done <-chan struct{}) bool {
+	decoder := json.NewDecoder(r)
+
+	if err := otlpconvert.SeekEntries(decoder); err != nil {
+		fatal(err.Error())
+	}
+
+	for decoder.More() {
+		var entry otlpconvert.BadgerEntry
+		if err := decoder.Decode(&entry); err != nil {
+			slog.Warn("failed to decode entry", "error", err)
+			continue
+		}
+
+		if !queueEntry(entry, entryChan, processed, config, done) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// readNDJSON streams entries from a file with one JSON entry per line into
+// entryChan, adding them to processed. Blank lines are skipped, and a line
+// holding an OTLP span instead of an entry stops the run. It returns false
+// once the configured entry limit is reached or done is closed.
+func readNDJSON(r io.Reader, entryChan chan<- otlpconvert.BadgerEntry, processed *atomic.Int64, config *otlpconvert.Config, done <-chan struct{}) bool {
+	reader := bufio.NewReaderSize(r, 1<<20)
+
+	lineNum := 0
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			fatal("failed to read input", "error", readErr)
+		}
+		lineNum++
+
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			entry, err := otlpconvert.DecodeNDJSONEntry(line)
+			switch {
+			case errors.Is(err, otlpconvert.ErrOTLPSpanLine):
+				fatal(fmt.Sprintf("line %d %v", lineNum, err))
+			case err != nil:
+				slog.Warn("failed to decode entry", "line", lineNum, "error", err)
+			case !queueEntry(entry, entryChan, processed, config, done):
+				return false
+			}
+		}
+
+		if readErr == io.EOF {
+			return true
+		}
+	}
+}
+
+// readZipkin streams spans from a Zipkin v2 JSON file into entryChan, adding
+// them to processed. The file holds an array of spans, or an array of traces
+// that are arrays of spans (as returned by /api/v2/traces). Each span becomes
+// an entry whose value is the span's JSON. It returns false once the
+// configured entry limit is reached or done is closed.
+func readZipkin(r io.Reader, entryChan chan<- otlpconvert.BadgerEntry, processed *atomic.Int64, config *otlpconvert.Config, done <-chan struct{}) bool {
+	decoder := json.NewDecoder(r)
+
+	token, err := decoder.Token()
+	if err != nil {
+		fatal("failed to read JSON", "error", err)
+	}
+	if token != json.Delim('[') {
+		fatal("input does not look like Zipkin JSON: top level is not an array")
+	}
+
+	for decoder.More() {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			fatal("failed to read JSON", "error", err)
+		}
+
+		spans := []json.RawMessage{raw}
+		if len(raw) > 0 && raw[0] == '[' {
+			spans = nil
+			if err := json.Unmarshal(raw, &spans); err != nil {
+				slog.Warn("failed to decode trace", "error", err)
+				continue
+			}
+		}
+		for _, span := range spans {
+			entry := otlpconvert.BadgerEntry{Value: otlpconvert.EntryValue(span)}
+			if !queueEntry(entry, entryChan, processed, config, done) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// queueEntry sends an entry to the workers, reports progress, and returns
+// false once the configured entry limit is reached or done is closed. It may
+// be called from several readers at once; processed is shared between them.
+func queueEntry(entry otlpconvert.BadgerEntry, entryChan chan<- otlpconvert.BadgerEntry, processed *atomic.Int64, config *otlpconvert.Config, done <-chan struct{}) bool {
+	// Entries already covered by a checkpoint are skipped when resuming
+	// (never with concurrent readers, see Config.InputWorkers)
+	if config.SkipEntries > 0 {
+		config.SkipEntries--
+		return true
+	}
+
+	// Claim a place under the entry limit before sending, so concurrent
+	// readers cannot overshoot it together
+	queued := processed.Add(1)
+	limit := int64(config.MaxEntries)
+	if limit > 0 && queued > limit {
+		processed.Add(-1)
+		return false
+	}
+	// Numbered in queue order for the checkpoint; a single reader sends in
+	// that order, and concurrent readers run without a checkpoint
+	entry.Seq = queued - 1
+
+	select {
+	case <-done:
+		processed.Add(-1)
+		return false
+	default:
+	}
+	select {
+	case entryChan <- entry:
+	case <-done:
+		processed.Add(-1)
+		return false
+	}
+
+	if limit > 0 && queued >= limit {
+		return false
+	}
+
+	if queued%10000 == 0 {
+		slog.Info("queued entries", "entries", queued)
+	}
+
+	return true
+}
+
+// gunzipIfCompressed returns a reader of the decompressed data if r starts
+// with the gzip magic bytes, and a reader of r's data otherwise
+func gunzipIfCompressed(r io.Reader, filename string) io.Reader {
+	buffered := bufio.NewReader(r)
+	magic, _ := buffered.Peek(2)
+	if !bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		return buffered
+	}
+	gz, err := gzip.NewReader(buffered)
+	if err != nil {
+		fatal("failed to read gzip input", "filename", redactURL(filename), "error", err)
+	}
+	return gz
+}
+
+// readInputs reads each input file in turn into entryChan and returns the
+// total number of entries queued. Files are read in order so that checkpoint
+// entry counts stay valid across runs. With a non-nil stop the last file is
+// followed for appended entries until stop is closed. Closing done ends
+// reading right away, e.g. once -limit-batches is reached. With
+// -input-workers above 1 the files are read concurrently instead.
+func readInputs(files []string, entryChan chan<- otlpconvert.BadgerEntry, config *otlpconvert.Config, stop, done <-chan struct{}) int {
+	var processed atomic.Int64
+	if config.InputWorkers > 1 && len(files) > 1 {
+		readInputsConcurrently(files, entryChan, &processed, config, done)
+		return int(processed.Load())
+	}
+
+	for i, filename := range files {
+		var follow <-chan struct{}
+		if i == len(files)-1 {
+			follow = stop
+		}
+		if !readInputFile(filename, entryChan, &processed, config, follow, done) {
+			break
+		}
+	}
+	return int(processed.Load())
+}
+
+// readInputsConcurrently reads up to -input-workers files at a time, each
+// with its own decoder goroutine feeding entryChan. Entries of different
+// files interleave, so their order (and with it the checkpoint entry count)
+// is not reproducible; the collector batches them as they arrive either way.
+func readInputsConcurrently(files []string, entryChan chan<- otlpconvert.BadgerEntry, processed *atomic.Int64, config *otlpconvert.Config, done <-chan struct{}) {
+	fileChan := make(chan string)
+	stopped := make(chan struct{}) // closed once a reader hits the entry limit
+	var stopOnce sync.Once
+	var wg sync.WaitGroup
+	for i := 0; i < min(config.InputWorkers, len(files)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for filename := range fileChan {
+				if !readInputFile(filename, entryChan, processed, config, nil, done) {
+					stopOnce.Do(func() { close(stopped) })
+				}
+			}
+		}()
+	}
+
+dispatch:
+	for _, filename := range files {
+		select {
+		case fileChan <- filename:
+		case <-stopped:
+			break dispatch
+		case <-done:
+			break dispatch
+		}
+	}
+	close(fileChan)
+	wg.Wait()
+}
+
+// readInputFile reads one input file or http(s) URL into entryChan,
+// following it for appended entries until follow is closed if follow is
+// non-nil. Gzip-compressed input is detected and unpacked. It returns false
+// once no more input is wanted.
+func readInputFile(filename string, entryChan chan<- otlpconvert.BadgerEntry, processed *atomic.Int64, config *otlpconvert.Config, follow, done <-chan struct{}) bool {
+	var r io.Reader
+	if otlpconvert.IsInputURL(filename) {
+		slog.Info("reading input", "url", redactURL(filename), "workers", config.NumWorkers, "batch_size", config.BatchSize)
+		in, err := openURL(filename, config.InputRetries)
+		if err != nil {
+			fatal("failed to fetch input", "url", redactURL(filename), "error", err)
+		}
+		defer in.Close()
+		r = in
+	} else {
+		slog.Info("reading input", "filename", filename, "workers", config.NumWorkers, "batch_size", config.BatchSize)
+		file, err := os.Open(filename)
+		if err != nil {
+			fatal("failed to open input", "filename", filename, "error", err)
+		}
+		defer file.Close()
+		r = file
+	}
+
+	if follow != nil {
+		slog.Info("following input for new entries", "filename", filename)
+		r = &followReader{file: r.(*os.File), stop: follow}
+	} else {
+		r = gunzipIfCompressed(r, filename)
+	}
+
+	switch config.InputFormat {
+	case "ndjson":
+		return readNDJSON(r, entryChan, processed, config, done)
+	case "zipkin":
+		return readZipkin(r, entryChan, processed, config, done)
+	default: // "badger"
+		return readBadgerExport(r, entryChan, processed, config, done)
+	}
+}
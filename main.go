@@ -1,97 +1,135 @@
 package main
 
 import (
-	"encoding/json"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"runtime"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+
+	"otlp-converter-go/pkg/otlpconvert"
 )
 
-type Config struct {
-	InputFile      string
-	OutputFile     string
-	MaxEntries     int
-	NumWorkers     int
-	BatchSize      int
-	WriteInterval  int
-	OutputFormat   string // "arrow" or "json" or "both"
-}
-
-type BadgerExport struct {
-	Entries []BadgerEntry `json:"entries"`
-}
-
-type BadgerEntry struct {
-	Key   string `json:"key"`
-	Value string `json:"value"` // hex-encoded protobuf
-}
+// summaryTopServices is how many services the summary lists by span count
+const summaryTopServices = 10
 
 func main() {
 	config := parseFlags()
+	if err := config.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		flag.Usage()
+		os.Exit(2)
+	}
 
-	fmt.Println("=" + string(make([]byte, 78)) + "=")
-	fmt.Println("OTLP CONVERTER - GO (BLAZING FAST)")
-	fmt.Println("=" + string(make([]byte, 78)) + "=")
-	fmt.Printf("CPU cores: %d\n", runtime.NumCPU())
-	fmt.Printf("Workers: %d\n", config.NumWorkers)
-	fmt.Printf("Batch size: %d\n", config.BatchSize)
-	fmt.Println()
-	fmt.Println("ADVANTAGES:")
-	fmt.Println("  ✓ Native protobuf parsing (50-100x faster than Python)")
-	fmt.Println("  ✓ True parallelism with goroutines (no GIL)")
-	fmt.Println("  ✓ Low memory overhead")
-	fmt.Println("  ✓ Full OTLP format in Arrow files")
-	fmt.Println("=" + string(make([]byte, 78)) + "=")
-	fmt.Println()
+	setupLogger(config)
+
+	if config.Benchmark > 0 {
+		runBenchmark(config, config.Benchmark)
+		return
+	}
+
+	// The decorative banner and summary are only for interactive text output
+	interactive := config.LogFormat == "text"
+	separator := strings.Repeat("=", 80)
+	if interactive {
+		// Banner goes to stderr so stdout can carry machine-readable output
+		fmt.Fprintln(os.Stderr, separator)
+		fmt.Fprintln(os.Stderr, "OTLP CONVERTER - GO (BLAZING FAST)")
+		fmt.Fprintln(os.Stderr, separator)
+		fmt.Fprintf(os.Stderr, "CPU cores: %d\n", runtime.NumCPU())
+		fmt.Fprintf(os.Stderr, "Workers: %d\n", config.NumWorkers)
+		fmt.Fprintf(os.Stderr, "Batch size: %d\n", config.BatchSize)
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "ADVANTAGES:")
+		fmt.Fprintln(os.Stderr, "  ✓ Native protobuf parsing (50-100x faster than Python)")
+		fmt.Fprintln(os.Stderr, "  ✓ True parallelism with goroutines (no GIL)")
+		fmt.Fprintln(os.Stderr, "  ✓ Low memory overhead")
+		fmt.Fprintln(os.Stderr, "  ✓ Full OTLP format in Arrow files")
+		fmt.Fprintln(os.Stderr, separator)
+		fmt.Fprintln(os.Stderr)
+	}
 
 	startTime := time.Now()
 
-	// Open input file
-	fmt.Printf("Reading: %s\n", config.InputFile)
-	file, err := os.Open(config.InputFile)
-	if err != nil {
-		log.Fatalf("Error opening file: %v", err)
+	// Expand the input glob/list; Validate already checked it matches
+	var inputFiles []string
+	if config.Generate == 0 {
+		files, err := config.InputFiles()
+		if err != nil {
+			fatal("failed to resolve input", "input", config.InputFile, "error", err)
+		}
+		inputFiles = files
 	}
-	defer file.Close()
 
-	// Create converter
-	converter := NewConverter(config)
+	// Loaded before -stats so its service counts see resolved processes
+	if config.ProcessMapFile != "" {
+		processes, err := otlpconvert.LoadProcessMap(config.ProcessMapFile)
+		if err != nil {
+			fatal("failed to load process map", "filename", config.ProcessMapFile, "error", err)
+		}
+		config.ProcessMap = processes
+		slog.Info("loaded process map", "filename", config.ProcessMapFile, "processes", len(processes))
+	}
 
-	// Start background writer
-	writerDone := make(chan struct{})
-	go converter.BackgroundWriter(writerDone)
+	if config.Stats {
+		runStats(config, inputFiles)
+		return
+	}
 
-	// Read and parse entries
-	decoder := json.NewDecoder(file)
+	if config.RenameMapFile != "" {
+		renames, err := otlpconvert.LoadRenameMap(config.RenameMapFile)
+		if err != nil {
+			fatal("failed to load rename map", "filename", config.RenameMapFile, "error", err)
+		}
+		config.RenameMap = renames
+		slog.Info("loaded rename map", "filename", config.RenameMapFile, "keys", len(renames))
+	}
 
-	// Read opening brace
-	if _, err := decoder.Token(); err != nil {
-		log.Fatalf("Error reading JSON: %v", err)
+	if config.Peek > 0 {
+		runPeek(config, inputFiles, config.Peek)
+		return
 	}
 
-	// Find entries array
-	for decoder.More() {
-		token, err := decoder.Token()
+	// Create converter
+	converter := otlpconvert.New(*config)
+
+	if config.Resume {
+		checkpoint, err := otlpconvert.LoadCheckpoint(config.CheckpointFile)
 		if err != nil {
-			log.Fatalf("Error reading JSON: %v", err)
+			fatal("failed to resume", "error", err)
 		}
+		converter.ResumeFrom(checkpoint)
+		config.SkipEntries = checkpoint.Entries
+		slog.Info("resuming from checkpoint", "entries", checkpoint.Entries, "batch", checkpoint.BatchCount)
+	}
 
-		if token == "entries" {
-			// Read array opening bracket
-			if _, err := decoder.Token(); err != nil {
-				log.Fatalf("Error reading entries array: %v", err)
-			}
-			break
+	if config.MetricsAddr != "" {
+		startMetricsServer(config.MetricsAddr, converter)
+		slog.Info("serving metrics", "addr", config.MetricsAddr)
+	}
+
+	// A marker left by an earlier run would announce this one as finished
+	if config.DoneMarker {
+		if err := config.RemoveDoneMarker(); err != nil {
+			fatal("failed to remove old done marker", "filename", config.DoneMarkerFile(), "error", err)
 		}
 	}
 
+	// Start background writers
+	writersDone := make([]chan struct{}, config.IOWorkers)
+	for i := range writersDone {
+		writersDone[i] = make(chan struct{})
+		go converter.BackgroundWriter(writersDone[i])
+	}
+
 	// Process entries in parallel
-	entryChan := make(chan BadgerEntry, config.BatchSize)
-	resultChan := make(chan *OTLPSpan, config.BatchSize*2)
+	entryChan := make(chan otlpconvert.BadgerEntry, config.EntryQueue)
+	resultChan := make(chan *otlpconvert.OTLPSpan, config.ResultQueue)
 
 	// Start workers
 	var wg sync.WaitGroup
@@ -104,25 +142,16 @@ func main() {
 	collectorDone := make(chan struct{})
 	go converter.ResultCollector(resultChan, collectorDone)
 
-	// Stream entries from JSON
-	processed := 0
-	for decoder.More() {
-		var entry BadgerEntry
-		if err := decoder.Decode(&entry); err != nil {
-			log.Printf("Error decoding entry: %v", err)
-			continue
-		}
-
-		entryChan <- entry
-		processed++
-
-		if config.MaxEntries > 0 && processed >= config.MaxEntries {
-			break
-		}
-
-		if processed%10000 == 0 {
-			fmt.Printf("Queued %d entries...\n", processed)
-		}
+	// Stream entries from every input file into the same pipeline
+	var stop <-chan struct{}
+	if config.Follow {
+		stop = followStop()
+	}
+	var processed int
+	if config.Generate > 0 {
+		processed = generateEntries(config.Generate, config.Seed, entryChan, config, converter.StopInput())
+	} else {
+		processed = readInputs(inputFiles, entryChan, config, stop, converter.StopInput())
 	}
 
 	// Shutdown sequence
@@ -131,48 +160,458 @@ func main() {
 	close(resultChan)
 	<-collectorDone
 
-	// Signal writer to finish
+	// Signal writers to finish
 	converter.Shutdown()
-	<-writerDone
+	for _, done := range writersDone {
+		<-done
+	}
 
 	elapsed := time.Since(startTime)
 
-	fmt.Println()
-	fmt.Println("=" + string(make([]byte, 78)) + "=")
-	fmt.Println("✓ CONVERSION COMPLETE")
-	fmt.Printf("  Total entries processed: %d\n", processed)
-	fmt.Printf("  Total spans written: %d\n", converter.TotalSpans())
-	fmt.Printf("  Total time: %.1fs\n", elapsed.Seconds())
-	fmt.Printf("  Rate: %.0f spans/sec\n", float64(converter.TotalSpans())/elapsed.Seconds())
-	fmt.Printf("  Batch files: %d\n", converter.BatchCount())
-	fmt.Println("=" + string(make([]byte, 78)) + "=")
-	fmt.Println()
+	if converter.UnknownTagTypes() > 0 {
+		slog.Warn("tags with unknown value types were converted as strings", "tags", converter.UnknownTagTypes())
+	}
+
+	// Frequent synchronous writes mean the run is IO-bound
+	if backpressureHigh(converter) {
+		slog.Warn("writer could not keep up; consider a larger -write-interval or fewer -workers",
+			"writer_backpressure_events", converter.BackpressureEvents(),
+			"batches", converter.BatchCount(),
+		)
+	}
+
+	exportRate, rateLimitWait := converter.ExportRate()
+
+	failed := runFailed(converter, config)
+	// Written last, once the writers are done, so it marks complete output
+	if config.DoneMarker && !failed {
+		if err := converter.WriteDoneMarker(int64(processed)); err != nil {
+			slog.Error("failed to write done marker", "filename", config.DoneMarkerFile(), "error", err)
+			failed = true
+		}
+	}
+	status := "ok"
+	if failed {
+		status = "partial_failure"
+	}
+
+	if !interactive {
+		slog.Info("conversion complete",
+			"status", status,
+			"entries", processed,
+			"spans", converter.TotalSpans(),
+			"batches", converter.BatchCount(),
+			"batch_limit_hit", converter.BatchLimitHit(),
+			"parse_errors", converter.ParseErrors(),
+			"writer_backpressure_events", converter.BackpressureEvents(),
+			"invalid_timestamps", converter.InvalidTimestamps(),
+			"malformed_ids", converter.MalformedIDs(),
+			"attr_conflicts", converter.AttrConflicts(),
+			"otel_native_spans", converter.OTelNativeSpans(),
+			"end_before_start", converter.EndBeforeStart(),
+			"trace_limit_drops", converter.TraceLimitDrops(),
+			"max_trace_spans", converter.MaxTraceSpans(),
+			"memory_flushes", converter.MemoryFlushes(),
+			"outside_time_range", converter.OutsideTimeRange(),
+			"unknown_service_drops", converter.UnknownServiceDrops(),
+			"unresolved_processes", converter.UnresolvedProcesses(),
+			"invalid_spans", converter.InvalidSpans(),
+			"otlp_violations", converter.OTLPViolations(),
+			"trace_id_filtered", converter.TraceIDFiltered(),
+			"trace_files", converter.TraceFiles(),
+			"trace_file_drops", converter.TraceFileDrops(),
+			"sampled_out", converter.SampledOut(),
+			"distinct_traces", converter.DistinctTraces(),
+			"distinct_services", converter.DistinctServices(),
+			"service_spans", converter.ServiceSpans(),
+			"distinct_processes", converter.DistinctProcesses(),
+			"lost_batches", converter.LostBatches(),
+			"export_spans_per_sec", int64(exportRate),
+			"rate_limit_wait", rateLimitWait.Round(time.Millisecond).String(),
+			"elapsed", elapsed.Round(time.Millisecond).String(),
+			"spans_per_sec", int64(float64(converter.TotalSpans())/elapsed.Seconds()),
+		)
+		if config.ProfileParse {
+			slog.Info("parse latency",
+				"p50", converter.ParseLatency(50).String(),
+				"p95", converter.ParseLatency(95).String(),
+				"p99", converter.ParseLatency(99).String(),
+			)
+		}
+		if failed {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// With -output - stdout carries the export, so the summary moves aside
+	summary := os.Stdout
+	if config.WritesToStdout() {
+		summary = os.Stderr
+	}
+	fmt.Fprintln(summary)
+	fmt.Fprintln(summary, separator)
+	if failed {
+		fmt.Fprintln(summary, "✗ CONVERSION FINISHED WITH ERRORS")
+	} else {
+		fmt.Fprintln(summary, "✓ CONVERSION COMPLETE")
+	}
+	fmt.Fprintf(summary, "  Total entries processed: %d\n", processed)
+	fmt.Fprintf(summary, "  Total spans written: %d\n", converter.TotalSpans())
+	fmt.Fprintf(summary, "  Total time: %.1fs\n", elapsed.Seconds())
+	fmt.Fprintf(summary, "  Rate: %.0f spans/sec\n", float64(converter.TotalSpans())/elapsed.Seconds())
+	fmt.Fprintf(summary, "  Batch files: %d\n", converter.BatchCount())
+	if converter.BatchLimitHit() {
+		fmt.Fprintf(summary, "  Stopped at -limit-batches %d; the rest of the input was not read\n", config.LimitBatches)
+	}
+	fmt.Fprintf(summary, "  Distinct traces: %d, Distinct services: %d\n", converter.DistinctTraces(), converter.DistinctServices())
+	fmt.Fprintf(summary, "  Writer backpressure events: %d\n", converter.BackpressureEvents())
+	fmt.Fprintf(summary, "  Parse errors: %d (allowed: %d)\n", converter.ParseErrors(), config.MaxErrors)
+	fmt.Fprintf(summary, "  Invalid timestamps: %d\n", converter.InvalidTimestamps())
+	if converter.EndBeforeStart() > 0 {
+		fmt.Fprintf(summary, "  Spans ending before they start (%s): %d\n", config.EndBeforeStart, converter.EndBeforeStart())
+	}
+	if converter.MalformedIDs() > 0 {
+		fmt.Fprintf(summary, "  Spans with malformed IDs: %d\n", converter.MalformedIDs())
+	}
+	if config.OTelNativeTags {
+		fmt.Fprintf(summary, "  Spans with otel.* tags mapped back: %d\n", converter.OTelNativeSpans())
+	}
+	if converter.AttrConflicts() > 0 {
+		fmt.Fprintf(summary, "  Row attributes dropped as span/resource duplicates (%s kept): %d\n", config.AttrPrecedence, converter.AttrConflicts())
+	}
+	if !config.Since.IsZero() || !config.Until.IsZero() {
+		fmt.Fprintf(summary, "  Spans outside -since/-until: %d\n", converter.OutsideTimeRange())
+	}
+	if config.OneFilePerTrace {
+		fmt.Fprintf(summary, "  Trace files: %d\n", converter.TraceFiles())
+		if converter.TraceFileDrops() > 0 {
+			fmt.Fprintf(summary, "  Spans skipped by -max-trace-files: %d\n", converter.TraceFileDrops())
+		}
+	}
+	if config.DropUnknownService {
+		fmt.Fprintf(summary, "  Spans dropped by -drop-unknown-service: %d\n", converter.UnknownServiceDrops())
+	}
+	if config.ProcessMapFile != "" {
+		fmt.Fprintf(summary, "  Spans with a process ID missing from -process-map: %d\n", converter.UnresolvedProcesses())
+	}
+	if config.ValidateOTLP {
+		action := "kept"
+		if config.Strict {
+			action = "dropped"
+		}
+		fmt.Fprintf(summary, "  Spans failing -validate-otlp (%s): %d\n", action, converter.InvalidSpans())
+		violations := converter.OTLPViolations()
+		kinds := make([]string, 0, len(violations))
+		for kind := range violations {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+		for _, kind := range kinds {
+			fmt.Fprintf(summary, "    %s: %d\n", kind, violations[kind])
+		}
+	}
+	if len(config.TraceIDs) > 0 {
+		fmt.Fprintf(summary, "  Spans not matching -trace-id: %d\n", converter.TraceIDFiltered())
+	}
+	if config.Sample > 0 && config.Sample < 1 {
+		fmt.Fprintf(summary, "  Spans dropped by -sample %g: %d\n", config.Sample, converter.SampledOut())
+	}
+	if config.MaxSpansPerTrace > 0 {
+		fmt.Fprintf(summary, "  Spans dropped by -max-spans-per-trace: %d\n", converter.TraceLimitDrops())
+	}
+	if config.KeepTracesTogether {
+		fmt.Fprintf(summary, "  Largest trace: %d spans\n", converter.MaxTraceSpans())
+	}
+	if config.MaxMemoryMB > 0 {
+		fmt.Fprintf(summary, "  Flushes forced by -max-memory-mb: %d\n", converter.MemoryFlushes())
+	}
+	if config.RateLimit > 0 {
+		fmt.Fprintf(summary, "  Export rate: %.0f spans/sec (limit %d, held back %.1fs)\n", exportRate, config.RateLimit, rateLimitWait.Seconds())
+	}
+	if converter.LostBatches() > 0 {
+		fmt.Fprintf(summary, "  Lost batch files: %d (see errors above)\n", converter.LostBatches())
+	}
+	if config.DedupProcesses {
+		fmt.Fprintf(summary, "  Distinct processes: %d\n", converter.DistinctProcesses())
+	}
+	if config.ProfileParse {
+		fmt.Fprintf(summary, "  Parse latency: p50 %v, p95 %v, p99 %v\n",
+			converter.ParseLatency(50), converter.ParseLatency(95), converter.ParseLatency(99))
+	}
+	if serviceSpans := converter.ServiceSpans(); len(serviceSpans) > 0 {
+		var collected int64
+		for _, n := range serviceSpans {
+			collected += n
+		}
+		fmt.Fprintln(summary, "  Top services by spans:")
+		printHistogram(summary, serviceSpans, collected, summaryTopServices)
+	}
+	fmt.Fprintln(summary, separator)
+	fmt.Fprintln(summary)
+	outputBase := config.OutputFile
+	switch config.PartitionBy {
+	case "service":
+		outputBase += ".<service>"
+	case "minute", "hour", "day":
+		outputBase += ".<window>"
+	}
+	jsonExt := "otlp.json"
+	if config.GroupBy == "trace" {
+		jsonExt = "otlp.jsonl"
+	}
+	arrowOutput := outputBase + ".batch_NNNN.arrow"
+	if config.ArrowAppend {
+		arrowOutput = outputBase + ".arrow"
+	}
 	switch config.OutputFormat {
 	case "json":
-		fmt.Printf("Output: %s.batch_NNNN.otlp.json\n", config.OutputFile)
+		if config.WritesToStdout() {
+			fmt.Fprintln(summary, "Output: stdout")
+			break
+		}
+		if config.OneFilePerTrace {
+			fmt.Fprintf(summary, "Output: %s.<traceid>.otlp.json\n", outputBase)
+			break
+		}
+		fmt.Fprintf(summary, "Output: %s.batch_NNNN.%s\n", outputBase, jsonExt)
+	case "protobuf":
+		fmt.Fprintf(summary, "Output: %s.batch_NNNN.otlp.pb\n", outputBase)
+	case "csv":
+		fmt.Fprintf(summary, "Output: %s.batch_NNNN.csv\n", outputBase)
+	case "clickhouse-tsv":
+		fmt.Fprintf(summary, "Output: %s.batch_NNNN.tsv\n", outputBase)
+	case "http":
+		fmt.Fprintf(summary, "Output: POST %s\n", config.Endpoint)
 	case "both":
-		fmt.Printf("Output: %s.batch_NNNN.arrow and %s.batch_NNNN.otlp.json\n", config.OutputFile, config.OutputFile)
+		fmt.Fprintf(summary, "Output: %s and %s.batch_NNNN.%s\n", arrowOutput, outputBase, jsonExt)
 	default:
-		fmt.Printf("Output: %s.batch_NNNN.arrow\n", config.OutputFile)
-		fmt.Println()
-		fmt.Println("Use Python to read:")
-		fmt.Println("  from load_arrow_traces import load_otlp_spans_from_arrow")
-		fmt.Println("  spans = load_otlp_spans_from_arrow('output.batch_0000.arrow')")
+		fmt.Fprintf(summary, "Output: %s\n", arrowOutput)
+		fmt.Fprintln(summary)
+		fmt.Fprintln(summary, "Use Python to read:")
+		fmt.Fprintln(summary, "  from load_arrow_traces import load_otlp_spans_from_arrow")
+		fmt.Fprintln(summary, "  spans = load_otlp_spans_from_arrow('output.batch_0000.arrow')")
+	}
+
+	if failed {
+		os.Exit(1)
 	}
 }
 
-func parseFlags() *Config {
-	config := &Config{}
+func parseFlags() *otlpconvert.Config {
+	config := &otlpconvert.Config{}
 
-	flag.StringVar(&config.InputFile, "input", "badger_export.json", "Input BadgerDB export file")
-	flag.StringVar(&config.OutputFile, "output", "traces_otlp", "Output base filename")
-	flag.StringVar(&config.OutputFormat, "format", "arrow", "Output format: arrow, json, or both")
+	flag.StringVar(&config.InputFile, "input", "badger_export.json", "Input BadgerDB export file, glob pattern (e.g. 'badger_export_*.json'), http(s):// or s3:// URL, or comma-separated list")
+	flag.StringVar(&config.OutputFile, "output", "traces_otlp", "Output base filename, s3://bucket/prefix to upload batches, or - to write a single OTLP JSON export to stdout (-format json)")
+	flag.StringVar(&config.OutputFormat, "format", "arrow", "Output format: arrow, json, protobuf, both, csv, clickhouse-tsv, or http (POST to -endpoint)")
+	flag.StringVar(&config.Endpoint, "endpoint", "", "OTLP/HTTP traces endpoint for -format http (e.g. https://collector:4318/v1/traces)")
+	flag.StringVar(&config.HTTPEncoding, "http-encoding", "protobuf", "Request body for -format http: protobuf or json")
+	config.Headers = make(map[string]string)
+	flag.Var(headerFlag(config.Headers), "header", "Extra request header for -format http as 'Key: Value' (repeatable)")
+	flag.IntVar(&config.RateLimit, "rate-limit", 0, "Most spans per second sent to -endpoint with -format http (0 = unlimited)")
+	flag.BoolVar(&config.Pretty, "pretty", true, "Indent OTLP JSON output (-pretty=false for compact JSON)")
+	flag.IntVar(&config.WriteRetries, "write-retries", 3, "Retries with exponential backoff for a failed batch file write")
+	flag.IntVar(&config.InputRetries, "input-retries", 3, "Retries with exponential backoff for a failed -input URL request")
+	flag.BoolVar(&config.ArrowAppend, "arrow-append", false, "Append every batch to <output>.arrow, creating it if needed, instead of writing batch files")
+	flag.IntVar(&config.ArrowChunkSize, "arrow-chunk-size", 65536, "Rows per Arrow record batch (0 = one record batch per file)")
+	flag.StringVar(&config.ArrowTimeType, "arrow-time-type", "none", "Add Arrow start_time/end_time columns: none, int (int64 nanoseconds), or timestamp (timestamp[ns, UTC])")
+	flag.StringVar(&config.ArrowSpanEncoding, "arrow-span-encoding", "json", "Encoding of the Arrow otlp_span column: json (string) or proto (binary OTLP Span message)")
+	compactIDs := flag.Bool("compact-traceid", false, "Store Arrow trace_id/span_id as 16/8-byte fixed-size binary (schema version 2) instead of hex strings")
+	flag.BoolVar(&config.Deterministic, "deterministic", false, "Sort traces by ID and spans by start time before writing, for reproducible output")
+	flag.BoolVar(&config.OneFilePerTrace, "one-file-per-trace", false, "Write each trace to <output>.<traceid>.otlp.json (json format; meant for small or -trace-id runs)")
+	flag.IntVar(&config.MaxTraceFiles, "max-trace-files", 100, "Most per-trace files -one-file-per-trace may create; further traces are skipped (0 = unlimited, requires -trace-id)")
+	flag.StringVar(&config.SchemaURL, "schema-url", "", "Semantic conventions schema URL set as schemaUrl on every ResourceSpans and ScopeSpans, e.g. https://opentelemetry.io/schemas/1.21.0")
+	flag.StringVar(&config.GroupBy, "group-by", "resource", "OTLP JSON layout: resource (one TracesData per batch) or trace (JSON Lines, one TracesData per trace)")
+	flag.StringVar(&config.PartitionBy, "partition-by", "none", "Split output files by: none, service, or a start-time window (minute, hour, day)")
+	flag.StringVar(&config.InputFormat, "input-format", "badger", "Input format: badger (export with entries array), ndjson (one entry per line), or zipkin (Zipkin v2 JSON spans)")
+	flag.StringVar(&config.ValueEncoding, "value-encoding", "hex", "Entry value encoding: hex, base64, or raw")
+	flag.StringVar(&config.ValueShape, "value-shape", "span", "Entry value contents: span (one Jaeger span) or spanlist (a Jaeger batch of spans)")
 	flag.IntVar(&config.MaxEntries, "max", 0, "Max entries to process (0 = all)")
+	flag.IntVar(&config.LimitBatches, "limit-batches", 0, "Stop reading once this many batches are written (0 = no limit)")
 	flag.IntVar(&config.NumWorkers, "workers", runtime.NumCPU(), "Number of workers")
+	flag.IntVar(&config.IOWorkers, "io-workers", 1, "Number of goroutines writing batch files concurrently")
+	flag.IntVar(&config.InputWorkers, "input-workers", 1, "Number of input files decoded concurrently (disables checkpointing above 1)")
 	flag.IntVar(&config.BatchSize, "batch", 200000, "Batch size for processing")
+	flag.IntVar(&config.EntryQueue, "entry-queue", 0, "Entries buffered ahead of the workers (default: -batch)")
+	flag.IntVar(&config.ResultQueue, "result-queue", 0, "Converted spans buffered ahead of the collector (default: 2 x -batch)")
 	flag.IntVar(&config.WriteInterval, "write-interval", 2000000, "Write to disk every N spans (default: 2M spans per file)")
+	flag.DurationVar(&config.FlushInterval, "flush-interval", 30*time.Second, "Flush buffered spans after this long without a flush, even below -write-interval (e.g. 5s, 2m)")
+	flag.IntVar(&config.MaxMemoryMB, "max-memory-mb", 0, "Flush buffered spans early whenever the heap exceeds this many MiB, regardless of -write-interval (0 = no limit)")
+
+	flag.BoolVar(&config.SkipInvalidTimestamps, "skip-invalid-timestamps", false, "Drop spans with a zero or pre-epoch start time instead of clamping them to the epoch")
+	flag.BoolVar(&config.OTelNativeTags, "otel-native-tags", false, "For spans translated from OTLP (with otel.status_code or otel.scope.name tags), map otel.* and related tags back to status, scope, kind and trace state instead of attributes")
+	flag.StringVar(&config.SpanKindDefault, "span-kind-default", "internal", "Kind of spans without a span.kind tag: server, client, producer, consumer, or internal")
+	flag.StringVar(&config.EndBeforeStart, "end-before-start", "clamp", "Spans whose end precedes their start (e.g. overflowing durations): clamp (zero duration) or flag (keep, add conversion.warning)")
+	flag.StringVar(&config.DefaultService, "default-service", "unknown", "Service name for spans whose process has none")
+	flag.BoolVar(&config.KeepSourceKey, "keep-source-key", false, "Add each entry's Badger key to its spans as a jaeger.badger_key attribute")
+	flag.BoolVar(&config.ValidateOTLP, "validate-otlp", false, "Check every converted span against OTLP invariants (IDs, name, kind, timestamps, attribute values) and report violations")
+	flag.BoolVar(&config.Strict, "strict", false, "With -validate-otlp, drop spans that fail validation and exit non-zero")
+	flag.BoolVar(&config.DropUnknownService, "drop-unknown-service", false, "Drop spans with no service name (after -service-from-tag) instead of naming them \"unknown\"")
+	flag.StringVar(&config.ServiceFromTag, "service-from-tag", "", "Tag key (process tags, then span tags) to take the service name from when the process has none")
+	flag.BoolVar(&config.FlattenJSONTags, "flatten-nested-json-tags", false, "Expand string tags holding a JSON object into dotted attributes (slower)")
+	flag.BoolVar(&config.DedupProcesses, "dedup-processes", false, "Convert each distinct Jaeger process once and share its resource attributes between spans")
+	flag.StringVar(&config.AttrPrecedence, "attr-precedence", "span", "Which attribute Arrow and CSV rows keep when a span tag and a process tag share a key: span or resource")
+	flag.StringVar(&config.JSONTagStyle, "json-tag-style", "dotted", "How -flatten-nested-json-tags expands objects: dotted (one attribute per field) or kvlist (one kvlistValue attribute)")
+	flag.StringVar(&config.NormalizeKeys, "normalize-attribute-keys", "none", "Rewrite tag keys not in -rename-map: none, dots (http_method -> http.method, keys without a dot only) or underscores (http.status_code -> http_status_code)")
+	flag.StringVar(&config.RenameMapFile, "rename-map", "", "JSON file mapping tag keys to OTLP attribute keys, e.g. {\"http.status\": \"http.status_code\"}")
+	flag.StringVar(&config.ProcessMapFile, "process-map", "", "JSON file mapping process IDs to processes (Jaeger query API form), for spans stored with a ProcessID instead of a Process")
+	flag.Func("redact", "Comma-separated tag keys whose values are replaced with [REDACTED] (repeatable)", func(value string) error {
+		config.RedactKeys = append(config.RedactKeys, splitList(value)...)
+		return nil
+	})
+	flag.Func("hash", "Comma-separated tag keys whose values are replaced with their SHA-256 hex (repeatable)", func(value string) error {
+		config.HashKeys = append(config.HashKeys, splitList(value)...)
+		return nil
+	})
+	config.ResourceAttributes = make(map[string]string)
+	flag.Var(resourceAttrFlag(config.ResourceAttributes), "resource-attr", "Resource attribute added to every span's resource as key=value (repeatable)")
+	flag.Var(timeFlag{&config.Since}, "since", "Only convert spans starting at or after this time (RFC3339 or unix nanoseconds)")
+	flag.Var(timeFlag{&config.Until}, "until", "Only convert spans starting before this time (RFC3339 or unix nanoseconds)")
+	flag.Func("trace-id", "Comma-separated hex trace IDs to convert; all other spans are dropped (repeatable)", func(value string) error {
+		config.TraceIDs = append(config.TraceIDs, splitList(value)...)
+		return nil
+	})
+	flag.Float64Var(&config.Sample, "sample", 1, "Fraction of traces to convert, chosen deterministically by trace ID: greater than 0 and at most 1 (e.g. 0.1); 0 is rejected")
+	flag.IntVar(&config.MaxAttrsPerSpan, "max-attrs-per-span", 0, "Keep at most this many attributes per span or event; the rest are counted in droppedAttributesCount (0 = unlimited)")
+	flag.IntVar(&config.MaxAttrValueLen, "max-attr-value-len", 0, "Truncate string attribute values to this many bytes (0 = unlimited)")
+	flag.IntVar(&config.MaxEventsPerSpan, "max-events-per-span", 0, "Keep at most this many events (Jaeger logs) per span; the rest are counted in droppedEventsCount (0 = unlimited)")
+	flag.StringVar(&config.EventSampling, "event-sampling", "first", "Events kept over -max-events-per-span: first (earliest) or even (spread across the span)")
+	flag.BoolVar(&config.KeepTracesTogether, "keep-traces-together", false, "Flush a full write buffer only when a new trace starts, so a trace's spans stay in one batch file (input must be grouped by trace)")
+	flag.IntVar(&config.MaxSpansPerTrace, "max-spans-per-trace", 0, "Drop spans beyond this many per trace within one write buffer (0 = unlimited)")
+	flag.Int64Var(&config.MaxErrors, "max-errors", 0, "Exit non-zero if more than this many entries fail to parse")
+	flag.StringVar(&config.LogFormat, "log-format", "text", "Log format: text or json")
+	flag.StringVar(&config.LogLevel, "log-level", "info", "Log level: debug, info, warn, or error")
+	flag.BoolVar(&config.Stats, "stats", false, "Scan the input and print spans per service, span kinds, error rate and time range; no output is written")
+	flag.BoolVar(&config.Follow, "follow", false, "Keep reading the last input file as entries are appended, like tail -f, until SIGINT or SIGTERM")
+	flag.IntVar(&config.Peek, "peek", 0, "Print the first N converted spans to stdout as JSON and exit; no output is written")
+	flag.IntVar(&config.Benchmark, "benchmark", 0, "Convert N synthetic spans in memory and report spans/sec; no input is read and no output written")
+	flag.IntVar(&config.Generate, "generate", 0, "Convert and write N generated spans (varied services, kinds, errors and events) instead of reading -input")
+	flag.Int64Var(&config.Seed, "seed", 1, "Random seed for -generate; the same seed gives the same spans")
+	flag.BoolVar(&config.ProfileParse, "profile-parse", false, "Record parse latency per entry and report p50/p95/p99 in the summary")
+	flag.StringVar(&config.MetricsAddr, "metrics-addr", "", "Serve /healthz and /metrics on this address (e.g. :8080)")
+
+	flag.StringVar(&config.CheckpointFile, "checkpoint", "", "Checkpoint file (default: <output>.checkpoint)")
+	flag.BoolVar(&config.Resume, "resume", false, "Resume an interrupted run from the checkpoint file")
+	flag.BoolVar(&config.DoneMarker, "done-marker", false, "Write <output>.done with final stats once every batch is written, only if the run succeeds")
 
 	flag.Parse()
 
+	// Queue depths follow the batch size unless set explicitly
+	if config.EntryQueue == 0 {
+		config.EntryQueue = config.BatchSize
+	}
+	if config.ResultQueue == 0 {
+		config.ResultQueue = config.BatchSize * 2
+	}
+	config.ArrowSchemaVersion = otlpconvert.ArrowSchemaHexIDs
+	if *compactIDs {
+		config.ArrowSchemaVersion = otlpconvert.ArrowSchemaCompactIDs
+	}
+	// Stdout output is one export written at the end, so there is nothing to
+	// checkpoint; concurrently read files have no resumable entry order. An
+	// s3:// output has no local place for one unless -checkpoint names it.
+	if config.CheckpointFile == "" && !config.WritesToStdout() && !otlpconvert.IsS3URL(config.OutputFile) && config.InputWorkers == 1 {
+		config.CheckpointFile = config.OutputFile + ".checkpoint"
+	}
+
 	return config
 }
+
+// splitList splits a comma-separated flag value, dropping empty elements
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// headerFlag collects repeatable -header "Key: Value" flags
+type headerFlag map[string]string
+
+func (h headerFlag) String() string {
+	return ""
+}
+
+func (h headerFlag) Set(value string) error {
+	key, val, ok := strings.Cut(value, ":")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		return fmt.Errorf("want 'Key: Value', got %q", value)
+	}
+	h[key] = strings.TrimSpace(val)
+	return nil
+}
+
+// resourceAttrFlag collects repeatable -resource-attr key=value flags
+type resourceAttrFlag map[string]string
+
+func (a resourceAttrFlag) String() string {
+	return ""
+}
+
+func (a resourceAttrFlag) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("want key=value, got %q", value)
+	}
+	a[key] = val
+	return nil
+}
+
+// timeFlag parses a -since/-until value given as RFC3339 or unix nanoseconds
+type timeFlag struct {
+	t *time.Time
+}
+
+func (f timeFlag) String() string {
+	if f.t == nil || f.t.IsZero() {
+		return ""
+	}
+	return f.t.Format(time.RFC3339Nano)
+}
+
+func (f timeFlag) Set(value string) error {
+	if nanos, err := strconv.ParseInt(value, 10, 64); err == nil {
+		*f.t = time.Unix(0, nanos).UTC()
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return fmt.Errorf("want RFC3339 or unix nanoseconds, got %q", value)
+	}
+	*f.t = t
+	return nil
+}
+
+// runFailed reports whether the run should exit non-zero so CI and cron jobs
+// notice: any lost batch file, more parse errors than -max-errors, or any span
+// failing -validate-otlp with -strict
+func runFailed(converter *otlpconvert.Converter, config *otlpconvert.Config) bool {
+	failed := false
+	if converter.DiskFull() {
+		slog.Error("output disk is full; the run stopped early, free space and rerun with -resume", "checkpoint", config.CheckpointFile)
+	}
+	if lost := converter.LostBatches(); lost > 0 {
+		slog.Error("batch files could not be written; output is incomplete", "lost_batches", lost)
+		failed = true
+	}
+	if errors := converter.ParseErrors(); errors > config.MaxErrors {
+		slog.Error("too many entries failed to parse", "parse_errors", errors, "max_errors", config.MaxErrors)
+		failed = true
+	}
+	if invalid := converter.InvalidSpans(); config.Strict && invalid > 0 {
+		slog.Error("spans failed OTLP validation and were dropped (-strict)", "invalid_spans", invalid, "violations", converter.OTLPViolations())
+		failed = true
+	}
+	return failed
+}
+
+// backpressureHigh reports whether at least 10% of batches were written
+// synchronously because the writer queue was full
+func backpressureHigh(converter *otlpconvert.Converter) bool {
+	events := converter.BackpressureEvents()
+	return events > 0 && events*10 >= int64(converter.BatchCount())
+}
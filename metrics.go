@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"otlp-converter-go/pkg/otlpconvert"
+)
+
+// startMetricsServer serves /healthz and Prometheus-format /metrics for a
+// running conversion
+func startMetricsServer(addr string, converter *otlpconvert.Converter) {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintln(w, "ok")
+	})
+
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writeMetric(w, "entries_processed", "counter", "Input entries handled by workers", converter.EntriesProcessed())
+		writeMetric(w, "spans_written", "counter", "Spans written to output files", int64(converter.TotalSpans()))
+		writeMetric(w, "parse_errors", "counter", "Entries whose value could not be decoded", converter.ParseErrors())
+		writeMetric(w, "batches_written", "counter", "Batch files written", int64(converter.BatchCount()))
+		writeMetric(w, "writer_backpressure_events", "counter", "Flushes written synchronously because the writer queue was full", converter.BackpressureEvents())
+		writeMetric(w, "buffered_traces", "gauge", "Traces waiting for the next flush", int64(converter.BufferedTraces()))
+	})
+
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			slog.Error("metrics server stopped", "addr", addr, "error", err)
+		}
+	}()
+}
+
+func writeMetric(w http.ResponseWriter, name, metricType, help string, value int64) {
+	name = "otlp_converter_" + name
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, metricType)
+	fmt.Fprintf(w, "%s %d\n", name, value)
+}
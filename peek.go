@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"otlp-converter-go/pkg/otlpconvert"
+)
+
+// runPeek converts entries in input order until n spans have been produced
+// and prints them to stdout as indented JSON. Each span is printed with its
+// resource attributes folded into its own, as in Arrow rows. Nothing is
+// written; reading stops as soon as n spans have been printed.
+func runPeek(config *otlpconvert.Config, inputFiles []string, n int) {
+	converter := otlpconvert.New(*config)
+	entryChan := make(chan otlpconvert.BadgerEntry, config.EntryQueue)
+
+	// The reader is left blocked once enough spans are printed; the process
+	// exits right after
+	go func() {
+		readInputs(inputFiles, entryChan, config, nil, nil)
+		close(entryChan)
+	}()
+
+	printed := 0
+	for entry := range entryChan {
+		for _, span := range converter.ConvertEntry(entry) {
+			if printed == n {
+				return
+			}
+			peekSpan := *span
+			peekSpan.Attributes = converter.RowAttributes(span)
+			data, err := json.MarshalIndent(&peekSpan, "", "  ")
+			if err != nil {
+				fatal("failed to encode span", "error", err)
+			}
+			fmt.Fprintln(os.Stdout, string(data))
+			printed++
+		}
+		if printed == n {
+			return
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package otlpconvert
+
+import (
+	"log/slog"
+	"os"
+)
+
+// arrowAppendFile is an -arrow-append output file being written this run
+type arrowAppendFile struct {
+	*arrowAppend
+	tmpFile string // where the file is written until finishArrowAppends
+	batches int    // batches appended this run
+	spans   int    // spans appended this run
+}
+
+// appendArrowBatch adds the rows of a batch to the Config.ArrowAppend file
+// filename. The file is opened on its first batch, copying the record
+// batches of the file left by an earlier run into <filename>.tmp once, and
+// stays open for the rest of the run; finishArrowAppends writes its footer
+// and renames it into place. Until then the earlier file is untouched.
+//
+// If an append fails, the open file is discarded along with the batches
+// already appended to it this run, which are counted as lost; the next batch
+// starts over from the earlier file.
+func (c *Converter) appendArrowBatch(filename string, rows []ArrowRow, opts ArrowWriteOptions) error {
+	c.arrowAppendLock.Lock()
+	defer c.arrowAppendLock.Unlock()
+
+	f := c.arrowAppends[filename]
+	if f == nil {
+		f = &arrowAppendFile{tmpFile: filename + ".tmp"}
+		err := ensureParentDir(filename)
+		if err == nil {
+			f.arrowAppend, err = openArrowAppend(filename, f.tmpFile, opts)
+		}
+		if err != nil {
+			os.Remove(f.tmpFile)
+			c.checkDiskFull(filename, err)
+			return err
+		}
+		if c.arrowAppends == nil {
+			c.arrowAppends = make(map[string]*arrowAppendFile)
+		}
+		c.arrowAppends[filename] = f
+	}
+
+	if err := f.write(rows, opts.ChunkSize); err != nil {
+		f.abort()
+		c.discardArrowAppend(filename, f)
+		c.checkDiskFull(filename, err)
+		return err
+	}
+	f.batches++
+	f.spans += len(rows)
+	return nil
+}
+
+// discardArrowAppend removes an unfinished append file and takes the batches
+// appended to it back out of the totals. The caller holds arrowAppendLock.
+func (c *Converter) discardArrowAppend(filename string, f *arrowAppendFile) {
+	os.Remove(f.tmpFile)
+	delete(c.arrowAppends, filename)
+	if f.batches == 0 {
+		return
+	}
+	slog.Error("discarded batches appended to Arrow file this run", "filename", filename, "batches", f.batches, "spans", f.spans)
+	c.lostBatches.Add(int64(f.batches))
+	c.statsLock.Lock()
+	c.totalSpans -= f.spans
+	c.statsLock.Unlock()
+}
+
+// finishArrowAppends writes the footer of every file opened by
+// appendArrowBatch and renames it over the file it extends, then saves the
+// checkpoint held back until the appended batches were durable. It runs once
+// the last BackgroundWriter has drained the write channel.
+func (c *Converter) finishArrowAppends() {
+	c.arrowAppendLock.Lock()
+	defer c.arrowAppendLock.Unlock()
+
+	for filename, f := range c.arrowAppends {
+		err := f.finish()
+		if err == nil {
+			err = os.Rename(f.tmpFile, filename)
+		}
+		if err != nil {
+			slog.Error("failed to finish Arrow file", "filename", filename, "error", err)
+			c.discardArrowAppend(filename, f)
+			c.checkDiskFull(filename, err)
+			continue
+		}
+		slog.Info("finished Arrow file", "filename", filename, "batches", f.batches, "spans", f.spans)
+	}
+	clear(c.arrowAppends)
+
+	if c.config.ArrowAppend {
+		c.writeCheckpoint()
+	}
+}
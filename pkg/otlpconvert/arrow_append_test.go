@@ -0,0 +1,85 @@
+package otlpconvert
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow/go/v14/arrow/ipc"
+	"github.com/apache/arrow/go/v14/arrow/memory"
+)
+
+// arrowFileRows returns the number of rows in the Arrow file filename
+func arrowFileRows(t *testing.T, filename string) int64 {
+	t.Helper()
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	reader, err := ipc.NewFileReader(f, ipc.WithAllocator(memory.NewGoAllocator()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	var rows int64
+	for i := 0; i < reader.NumRecords(); i++ {
+		record, err := reader.Record(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows += record.NumRows()
+	}
+	return rows
+}
+
+func TestArrowAppendRuns(t *testing.T) {
+	dir := t.TempDir()
+	prefix := filepath.Join(dir, "out")
+	filename := prefix + ".arrow"
+	config := Config{
+		OutputFile:         prefix,
+		OutputFormat:       "arrow",
+		ArrowAppend:        true,
+		ArrowSchemaVersion: ArrowSchemaHexIDs,
+	}
+
+	// The first run appends two batches to one file, which only appears
+	// once the run finishes
+	c := New(config)
+	c.writeToArrow(prefix, benchmarkTraces(c, 10), 0)
+	c.writeToArrow(prefix, benchmarkTraces(c, 20), 1)
+	if _, err := os.Stat(filename); err == nil {
+		t.Fatal("Arrow file written before the run finished")
+	}
+	c.finishArrowAppends()
+	if got := arrowFileRows(t, filename); got != 30 {
+		t.Errorf("rows after first run = %d, want 30", got)
+	}
+
+	// The second run keeps the rows of the first
+	c = New(config)
+	c.writeToArrow(prefix, benchmarkTraces(c, 5), 0)
+	c.finishArrowAppends()
+	if got := arrowFileRows(t, filename); got != 35 {
+		t.Errorf("rows after second run = %d, want 35", got)
+	}
+	if _, err := os.Stat(filename + ".tmp"); err == nil {
+		t.Error("temporary file left behind")
+	}
+	if c.LostBatches() != 0 {
+		t.Errorf("lost batches = %d, want 0", c.LostBatches())
+	}
+
+	// A run with a different schema loses its batch and leaves the file alone
+	config.ArrowSchemaVersion = ArrowSchemaCompactIDs
+	c = New(config)
+	if err := c.appendArrowBatch(filename, c.spanRows(benchmarkTraces(c, 5), false), ArrowWriteOptions{SchemaVersion: ArrowSchemaCompactIDs}); err == nil || !strings.Contains(err.Error(), "cannot append") {
+		t.Errorf("append with a different schema: error = %v", err)
+	}
+	c.finishArrowAppends()
+	if got := arrowFileRows(t, filename); got != 35 {
+		t.Errorf("rows after mismatched run = %d, want 35", got)
+	}
+}
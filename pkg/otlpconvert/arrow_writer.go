@@ -0,0 +1,373 @@
+package otlpconvert
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+
+	"github.com/apache/arrow/go/v14/arrow"
+	"github.com/apache/arrow/go/v14/arrow/array"
+	"github.com/apache/arrow/go/v14/arrow/ipc"
+	"github.com/apache/arrow/go/v14/arrow/memory"
+)
+
+type ArrowRow struct {
+	OTLPSpan      string
+	OTLPSpanProto []byte // serialized OTLP Span message, used with ArrowWriteOptions.SpanEncoding "proto"
+	TraceID       string
+	SpanID        string
+	TraceIDBytes  []byte // raw 16-byte trace ID, used by ArrowSchemaCompactIDs
+	SpanIDBytes   []byte // raw 8-byte span ID, used by ArrowSchemaCompactIDs
+	ServiceName   string
+	Name          string
+	StartTime     int64 // Unix nanoseconds, used with ArrowWriteOptions.TimeType
+	EndTime       int64 // Unix nanoseconds, used with ArrowWriteOptions.TimeType
+}
+
+// Arrow schema versions, recorded in the schema metadata under
+// "otlp_schema_version" so readers can tell the layouts apart
+const (
+	ArrowSchemaHexIDs     = 1 // trace_id and span_id as hex strings
+	ArrowSchemaCompactIDs = 2 // trace_id and span_id as fixed-size binary (16/8 bytes)
+)
+
+// ArrowWriteOptions controls the layout of Arrow output files
+type ArrowWriteOptions struct {
+	ChunkSize     int               // rows per record batch (0 = one batch)
+	SchemaVersion int               // ArrowSchemaHexIDs (default) or ArrowSchemaCompactIDs
+	Metadata      map[string]string // extra schema metadata, e.g. provenance
+	TimeType      string            // start_time/end_time columns: "" (none), "int" (int64 ns) or "timestamp" (timestamp[ns, UTC])
+	SpanEncoding  string            // otlp_span column: "" / "json" (JSON string) or "proto" (binary OTLP Span message)
+}
+
+// WriteArrowFile writes OTLP spans to Arrow IPC file format as a single
+// record batch
+func WriteArrowFile(filename string, rows []ArrowRow) error {
+	return WriteArrowFileOptions(filename, rows, ArrowWriteOptions{})
+}
+
+// arrowSchema returns the schema for a schema version, with start_time and
+// end_time columns appended when opts.TimeType is set and otlp_span as binary
+// with opts.SpanEncoding "proto". Its metadata holds the version, the
+// compression codec, the span encoding when it is not JSON, and any extra
+// key/values.
+func arrowSchema(opts ArrowWriteOptions) *arrow.Schema {
+	version := opts.SchemaVersion
+	var traceIDType, spanIDType arrow.DataType = arrow.BinaryTypes.String, arrow.BinaryTypes.String
+	if version == ArrowSchemaCompactIDs {
+		traceIDType = &arrow.FixedSizeBinaryType{ByteWidth: 16}
+		spanIDType = &arrow.FixedSizeBinaryType{ByteWidth: 8}
+	} else {
+		version = ArrowSchemaHexIDs
+	}
+	kv := make(map[string]string, len(opts.Metadata)+2)
+	for key, value := range opts.Metadata {
+		kv[key] = value
+	}
+	kv["otlp_schema_version"] = strconv.Itoa(version)
+	kv["compression"] = "lz4" // see newArrowFileWriter
+	var spanType arrow.DataType = arrow.BinaryTypes.String
+	if opts.SpanEncoding == "proto" {
+		spanType = arrow.BinaryTypes.Binary
+		kv["span_encoding"] = "proto"
+	}
+	metadata := arrow.MetadataFrom(kv)
+
+	// Define Arrow schema matching Python format
+	fields := []arrow.Field{
+		{Name: "otlp_span", Type: spanType, Nullable: false},
+		{Name: "trace_id", Type: traceIDType, Nullable: false},
+		{Name: "span_id", Type: spanIDType, Nullable: false},
+		{Name: "service_name", Type: arrow.BinaryTypes.String, Nullable: false},
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: false},
+	}
+	var timeType arrow.DataType
+	switch opts.TimeType {
+	case "int":
+		timeType = arrow.PrimitiveTypes.Int64
+	case "timestamp":
+		timeType = arrow.FixedWidthTypes.Timestamp_ns
+	}
+	if timeType != nil {
+		fields = append(fields,
+			arrow.Field{Name: "start_time", Type: timeType, Nullable: false},
+			arrow.Field{Name: "end_time", Type: timeType, Nullable: false},
+		)
+	}
+	return arrow.NewSchema(fields, &metadata)
+}
+
+// WriteArrowFileOptions writes OTLP spans to Arrow IPC file format, splitting
+// them into record batches of at most opts.ChunkSize rows. Each record is
+// released before the next is built, so peak memory is bounded by the chunk
+// size rather than the number of rows.
+func WriteArrowFileOptions(filename string, rows []ArrowRow, opts ArrowWriteOptions) error {
+	schema := arrowSchema(opts)
+
+	// Create memory allocator
+	mem := memory.NewGoAllocator()
+
+	// Write to file using Arrow IPC format (Feather v2)
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer file.Close()
+
+	writer, err := newArrowFileWriter(file, schema, mem)
+	if err != nil {
+		return err
+	}
+	if err := writeArrowRows(writer, schema, mem, rows, opts.ChunkSize); err != nil {
+		writer.Close()
+		return err
+	}
+	return finishArrowFile(writer, file)
+}
+
+// AppendArrowFile adds rows to an existing Arrow IPC file written with the
+// same options, or creates it like WriteArrowFileOptions if it does not
+// exist. The IPC file format ends in a footer indexing every record batch, so
+// the file is rewritten: existing batches are copied to a temporary file,
+// the new rows are added after them, and the result replaces the original.
+// A file with a different schema is left untouched and reported as an error.
+// The rewritten file carries the metadata of opts, not that of the original.
+func AppendArrowFile(filename string, rows []ArrowRow, opts ArrowWriteOptions) error {
+	tmpFile := filename + ".tmp"
+	defer os.Remove(tmpFile) // no-op once renamed
+	if err := appendArrowFile(filename, tmpFile, rows, opts); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, filename)
+}
+
+// appendArrowFile writes the record batches of src, if it exists, followed by
+// rows to dst
+func appendArrowFile(src, dst string, rows []ArrowRow, opts ArrowWriteOptions) error {
+	a, err := openArrowAppend(src, dst, opts)
+	if err != nil {
+		return err
+	}
+	if err := a.write(rows, opts.ChunkSize); err != nil {
+		a.abort()
+		return err
+	}
+	return a.finish()
+}
+
+// arrowAppend is an Arrow IPC file open for appending: the record batches of
+// the file it extends are copied in once, after which rows can be added any
+// number of times before finish writes the footer
+type arrowAppend struct {
+	file   *os.File
+	writer *ipc.FileWriter
+	schema *arrow.Schema
+	mem    memory.Allocator
+}
+
+// openArrowAppend creates dst holding the record batches of src, if it
+// exists, ready for more rows. A src with a different schema is reported as
+// an error before dst is created.
+func openArrowAppend(src, dst string, opts ArrowWriteOptions) (*arrowAppend, error) {
+	schema := arrowSchema(opts)
+	mem := memory.NewGoAllocator()
+
+	var reader *ipc.FileReader
+	existing, err := os.Open(src)
+	switch {
+	case err == nil:
+		defer existing.Close()
+		reader, err = ipc.NewFileReader(existing, ipc.WithAllocator(mem))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read existing Arrow file: %w", err)
+		}
+		defer reader.Close()
+		if err := checkArrowSchema(reader.Schema(), schema); err != nil {
+			return nil, fmt.Errorf("cannot append to %s: %w", src, err)
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	file, err := os.Create(dst)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file: %w", err)
+	}
+	writer, err := newArrowFileWriter(file, schema, mem)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	a := &arrowAppend{file: file, writer: writer, schema: schema, mem: mem}
+	if reader == nil {
+		return a, nil
+	}
+	for i := 0; i < reader.NumRecords(); i++ {
+		record, err := reader.Record(i)
+		if err != nil {
+			a.abort()
+			return nil, fmt.Errorf("failed to read existing record batch %d: %w", i, err)
+		}
+		if err := writer.Write(record); err != nil {
+			a.abort()
+			return nil, fmt.Errorf("failed to write record: %w", err)
+		}
+	}
+	return a, nil
+}
+
+// write adds rows in record batches of at most chunkSize rows
+func (a *arrowAppend) write(rows []ArrowRow, chunkSize int) error {
+	return writeArrowRows(a.writer, a.schema, a.mem, rows, chunkSize)
+}
+
+// finish writes the footer and closes the file
+func (a *arrowAppend) finish() error {
+	return finishArrowFile(a.writer, a.file)
+}
+
+// abort closes the file without finishing it; the caller removes it
+func (a *arrowAppend) abort() {
+	a.writer.Close()
+	a.file.Close()
+}
+
+// finishArrowFile writes the footer and closes the file, reporting failures
+// (such as a full disk) that would otherwise leave a truncated file behind
+func finishArrowFile(writer *ipc.FileWriter, file *os.File) error {
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to finish Arrow file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to finish Arrow file: %w", err)
+	}
+	return nil
+}
+
+// checkArrowSchema reports why an existing file's schema cannot take rows
+// written with want. Files from before schema versioning carry no version
+// metadata; they match version 1 if their columns do.
+func checkArrowSchema(got, want *arrow.Schema) error {
+	wantVersion := want.Metadata().Values()[want.Metadata().FindKey("otlp_schema_version")]
+	gotVersion := strconv.Itoa(ArrowSchemaHexIDs)
+	if i := got.Metadata().FindKey("otlp_schema_version"); i >= 0 {
+		gotVersion = got.Metadata().Values()[i]
+	}
+	if gotVersion != wantVersion {
+		return fmt.Errorf("file has Arrow schema version %s, writing version %s", gotVersion, wantVersion)
+	}
+	if len(got.Fields()) != len(want.Fields()) {
+		return fmt.Errorf("file has %d columns, want %d", len(got.Fields()), len(want.Fields()))
+	}
+	for i, field := range want.Fields() {
+		if g := got.Field(i); g.Name != field.Name || !arrow.TypeEqual(g.Type, field.Type) {
+			return fmt.Errorf("column %d is %s %s, want %s %s", i, g.Name, g.Type, field.Name, field.Type)
+		}
+	}
+	return nil
+}
+
+// newArrowFileWriter creates an LZ4-compressed IPC file writer
+func newArrowFileWriter(file *os.File, schema *arrow.Schema, mem memory.Allocator) (*ipc.FileWriter, error) {
+	writer, err := ipc.NewFileWriter(
+		file,
+		ipc.WithSchema(schema),
+		ipc.WithAllocator(mem),
+		ipc.WithLZ4(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Arrow writer: %w", err)
+	}
+	return writer, nil
+}
+
+// writeArrowRows writes rows as record batches of at most chunkSize rows
+// (0 = one batch)
+func writeArrowRows(writer *ipc.FileWriter, schema *arrow.Schema, mem memory.Allocator, rows []ArrowRow, chunkSize int) error {
+	builder := array.NewRecordBuilder(mem, schema)
+	defer builder.Release()
+
+	if chunkSize <= 0 {
+		chunkSize = len(rows)
+	}
+	// An empty file still gets one (empty) record batch
+	for start := 0; ; start += chunkSize {
+		end := min(start+chunkSize, len(rows))
+		if err := writeArrowChunk(writer, builder, rows[start:end]); err != nil {
+			return err
+		}
+		if end == len(rows) {
+			break
+		}
+	}
+	return nil
+}
+
+// writeArrowChunk appends rows to the builder and writes them as one record
+func writeArrowChunk(writer *ipc.FileWriter, builder *array.RecordBuilder, rows []ArrowRow) error {
+	// Populate columns
+	serviceNameBuilder := builder.Field(3).(*array.StringBuilder)
+	nameBuilder := builder.Field(4).(*array.StringBuilder)
+
+	for _, row := range rows {
+		serviceNameBuilder.Append(row.ServiceName)
+		nameBuilder.Append(row.Name)
+	}
+
+	// The span column is JSON text or proto bytes depending on the encoding
+	switch otlpSpanBuilder := builder.Field(0).(type) {
+	case *array.StringBuilder:
+		for _, row := range rows {
+			otlpSpanBuilder.Append(row.OTLPSpan)
+		}
+	case *array.BinaryBuilder:
+		for _, row := range rows {
+			otlpSpanBuilder.Append(row.OTLPSpanProto)
+		}
+	}
+
+	// ID columns are hex strings or raw bytes depending on the schema version
+	switch traceIDBuilder := builder.Field(1).(type) {
+	case *array.FixedSizeBinaryBuilder:
+		spanIDBuilder := builder.Field(2).(*array.FixedSizeBinaryBuilder)
+		for _, row := range rows {
+			traceIDBuilder.Append(row.TraceIDBytes)
+			spanIDBuilder.Append(row.SpanIDBytes)
+		}
+	case *array.StringBuilder:
+		spanIDBuilder := builder.Field(2).(*array.StringBuilder)
+		for _, row := range rows {
+			traceIDBuilder.Append(row.TraceID)
+			spanIDBuilder.Append(row.SpanID)
+		}
+	}
+
+	// Time columns are int64 nanoseconds or timestamps, if present at all
+	if len(builder.Fields()) > 6 {
+		switch startBuilder := builder.Field(5).(type) {
+		case *array.Int64Builder:
+			endBuilder := builder.Field(6).(*array.Int64Builder)
+			for _, row := range rows {
+				startBuilder.Append(row.StartTime)
+				endBuilder.Append(row.EndTime)
+			}
+		case *array.TimestampBuilder:
+			endBuilder := builder.Field(6).(*array.TimestampBuilder)
+			for _, row := range rows {
+				startBuilder.Append(arrow.Timestamp(row.StartTime))
+				endBuilder.Append(arrow.Timestamp(row.EndTime))
+			}
+		}
+	}
+
+	record := builder.NewRecord()
+	defer record.Release()
+
+	// Write record
+	if err := writer.Write(record); err != nil {
+		return fmt.Errorf("failed to write record: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package otlpconvert
+
+import (
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	jaeger "github.com/jaegertracing/jaeger/model"
+)
+
+// benchmarkTraces converts n benchmark spans spread over 100 traces
+func benchmarkTraces(c *Converter, n int) map[string][]*OTLPSpan {
+	traces := make(map[string][]*OTLPSpan)
+	for i := 1; i <= n; i++ {
+		span := benchmarkSpan(uint64(i))
+		span.TraceID = jaeger.NewTraceID(1, uint64(i%100+1))
+		otlp := c.ConvertJaegerSpan(span)
+		traces[otlp.TraceID] = append(traces[otlp.TraceID], otlp)
+	}
+	return traces
+}
+
+// quietLogs discards log output for the rest of b, so per-batch log lines
+// do not dominate the timings
+func quietLogs(b *testing.B) {
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	b.Cleanup(func() { slog.SetDefault(previous) })
+}
+
+// BenchmarkSpanRows measures serializing a 1000-span batch into Arrow rows,
+// which reuses one JSON encoder and row span for the whole batch
+func BenchmarkSpanRows(b *testing.B) {
+	c := New(Config{OutputFormat: "arrow"})
+	traces := benchmarkTraces(c, 1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.spanRows(traces, false)
+	}
+}
+
+// BenchmarkWriteToArrow measures writing a 1000-span batch file
+func BenchmarkWriteToArrow(b *testing.B) {
+	quietLogs(b)
+	c := New(Config{OutputFormat: "arrow"})
+	traces := benchmarkTraces(c, 1000)
+	prefix := filepath.Join(b.TempDir(), "out")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.writeToArrow(prefix, traces, 0)
+	}
+}
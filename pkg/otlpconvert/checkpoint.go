@@ -0,0 +1,190 @@
+package otlpconvert
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// Checkpoint records how far a conversion got, so an interrupted run can
+// resume without reprocessing entries or overwriting batch files
+type Checkpoint struct {
+	Entries    int64 `json:"entries"`    // input entries covered by written batches
+	BatchCount int   `json:"batchCount"` // next batch number to write
+}
+
+// LoadCheckpoint reads a checkpoint written by a previous run
+func LoadCheckpoint(filename string) (Checkpoint, error) {
+	var checkpoint Checkpoint
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return checkpoint, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return checkpoint, fmt.Errorf("failed to parse checkpoint: %w", err)
+	}
+
+	return checkpoint, nil
+}
+
+// ResumeFrom continues entry counting and batch numbering from a checkpoint.
+// It must be called before any entries are processed.
+func (c *Converter) ResumeFrom(checkpoint Checkpoint) {
+	c.statsLock.Lock()
+	c.batchCount = checkpoint.BatchCount
+	c.statsLock.Unlock()
+
+	c.entryOffset = checkpoint.Entries
+	c.checkpoint = checkpoint
+}
+
+// saveCheckpoint records a written batch. Batches may finish out of order
+// (with several writers, or when the collector writes synchronously), so
+// Entries only advances once every earlier flush is written too; otherwise
+// a crash could skip the entries of a batch still in flight.
+func (c *Converter) saveCheckpoint(seq, entries int64, batchNum int) {
+	if c.config.CheckpointFile == "" {
+		return
+	}
+	// Never move the checkpoint past a lost batch, so -resume redoes it
+	if c.lostBatches.Load() > 0 {
+		return
+	}
+
+	c.checkpointLock.Lock()
+	defer c.checkpointLock.Unlock()
+
+	if c.pendingFlushes == nil {
+		c.pendingFlushes = make(map[int64]int64)
+	}
+	c.pendingFlushes[seq] = entries
+	for {
+		done, ok := c.pendingFlushes[c.checkpointSeq]
+		if !ok {
+			break
+		}
+		delete(c.pendingFlushes, c.checkpointSeq)
+		c.checkpointSeq++
+		if done > c.checkpoint.Entries {
+			c.checkpoint.Entries = done
+		}
+	}
+	if batchNum+1 > c.checkpoint.BatchCount {
+		c.checkpoint.BatchCount = batchNum + 1
+	}
+
+	// Appended batches are only durable once finishArrowAppends completes
+	// the file, which saves the checkpoint then
+	if c.config.ArrowAppend {
+		return
+	}
+	c.saveCheckpointFile()
+}
+
+// writeCheckpoint saves the checkpoint as recorded so far, unless a batch
+// was lost
+func (c *Converter) writeCheckpoint() {
+	if c.config.CheckpointFile == "" || c.lostBatches.Load() > 0 {
+		return
+	}
+	c.checkpointLock.Lock()
+	defer c.checkpointLock.Unlock()
+	c.saveCheckpointFile()
+}
+
+// saveCheckpointFile writes c.checkpoint to Config.CheckpointFile. The
+// caller holds checkpointLock.
+func (c *Converter) saveCheckpointFile() {
+	data, err := json.Marshal(c.checkpoint)
+	if err != nil {
+		slog.Error("failed to encode checkpoint", "error", err)
+		return
+	}
+
+	// Write to a temp file and rename so a crash never leaves a torn checkpoint
+	tmpFile := c.config.CheckpointFile + ".tmp"
+	if err := ensureParentDir(tmpFile); err != nil {
+		slog.Error("failed to write checkpoint", "filename", c.config.CheckpointFile, "error", err)
+		return
+	}
+	if err := os.WriteFile(tmpFile, data, 0o644); err != nil {
+		slog.Error("failed to write checkpoint", "filename", c.config.CheckpointFile, "error", err)
+		return
+	}
+	if err := os.Rename(tmpFile, c.config.CheckpointFile); err != nil {
+		slog.Error("failed to write checkpoint", "filename", c.config.CheckpointFile, "error", err)
+	}
+}
+
+// entryProgress tracks which queued entries are fully handled, so the
+// checkpoint never claims an entry whose spans are not yet buffered. Workers
+// finish entries out of order and an entry may yield any number of spans, so
+// an entry is done only once a worker has handled it and the collector has
+// taken every span it sent on.
+type entryProgress struct {
+	mu      sync.Mutex
+	done    int64 // entries with Seq below this are all done
+	pending map[int64]*entryState
+}
+
+// entryState is the progress of one entry not yet counted in done
+type entryState struct {
+	parsed    bool // a worker has handled the entry
+	spans     int  // spans the worker sent to the collector
+	collected int  // of those, spans the collector has taken
+}
+
+func newEntryProgress() *entryProgress {
+	return &entryProgress{pending: make(map[int64]*entryState)}
+}
+
+// parsed records that a worker has handled entry seq and sent spans of its
+// spans on; the rest were filtered, or the entry failed to parse
+func (p *entryProgress) parsed(seq int64, spans int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	state := p.state(seq)
+	state.parsed = true
+	state.spans = spans
+	p.advance()
+}
+
+// collected records that the collector has taken a span of entry seq. It may
+// arrive before the worker reports the entry as parsed.
+func (p *entryProgress) collected(seq int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.state(seq).collected++
+	p.advance()
+}
+
+// covered returns the number of leading entries that are done
+func (p *entryProgress) covered() int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.done
+}
+
+func (p *entryProgress) state(seq int64) *entryState {
+	state, ok := p.pending[seq]
+	if !ok {
+		state = &entryState{}
+		p.pending[seq] = state
+	}
+	return state
+}
+
+// advance moves done past every finished entry at its front
+func (p *entryProgress) advance() {
+	for {
+		state, ok := p.pending[p.done]
+		if !ok || !state.parsed || state.collected < state.spans {
+			return
+		}
+		delete(p.pending, p.done)
+		p.done++
+	}
+}
@@ -0,0 +1,156 @@
+package otlpconvert
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	jaeger "github.com/jaegertracing/jaeger/model"
+)
+
+func TestEntryProgressCovered(t *testing.T) {
+	type event struct {
+		parsed bool // parsed(seq, spans) rather than collected(seq)
+		seq    int64
+		spans  int
+	}
+	tests := []struct {
+		name   string
+		events []event
+		want   int64
+	}{
+		{
+			name:   "in order",
+			events: []event{{true, 0, 1}, {false, 0, 0}, {true, 1, 1}, {false, 1, 0}},
+			want:   2,
+		},
+		{
+			name:   "parsed but not collected",
+			events: []event{{true, 0, 1}},
+			want:   0,
+		},
+		{
+			name:   "filtered or unparseable entries need no collection",
+			events: []event{{true, 0, 0}, {true, 1, 0}},
+			want:   2,
+		},
+		{
+			name:   "later entry finished first",
+			events: []event{{true, 1, 1}, {false, 1, 0}},
+			want:   0,
+		},
+		{
+			name:   "gap filled",
+			events: []event{{true, 1, 0}, {true, 2, 0}, {true, 0, 1}, {false, 0, 0}},
+			want:   3,
+		},
+		{
+			name:   "collected before parsed",
+			events: []event{{false, 0, 0}, {false, 0, 0}, {true, 0, 2}},
+			want:   1,
+		},
+		{
+			name:   "multi-span entry partly collected",
+			events: []event{{true, 0, 3}, {false, 0, 0}, {false, 0, 0}, {true, 1, 0}},
+			want:   0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newEntryProgress()
+			for _, e := range tt.events {
+				if e.parsed {
+					p.parsed(e.seq, e.spans)
+				} else {
+					p.collected(e.seq)
+				}
+			}
+			if got := p.covered(); got != tt.want {
+				t.Errorf("covered() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCheckpointCountsEntries runs the pipeline over entries that hold
+// several spans, some filtered, and checks the checkpoint records input
+// entries rather than spans
+func TestCheckpointCountsEntries(t *testing.T) {
+	dir := t.TempDir()
+	config := Config{
+		OutputFile:     filepath.Join(dir, "out"),
+		OutputFormat:   "json",
+		ValueEncoding:  "raw",
+		ValueShape:     "spanlist",
+		WriteInterval:  1000,
+		WriteRetries:   0,
+		CheckpointFile: filepath.Join(dir, "out.checkpoint"),
+		TraceIDs:       []string{"1"},
+	}
+	c := New(config)
+
+	// Each entry holds three spans; two are in trace 1 and the third is
+	// filtered by TraceIDs, so spans neither match entries one to one nor
+	// count every span read
+	const entries = 10
+	var queued []BadgerEntry
+	for i := 0; i < entries; i++ {
+		batch := &jaeger.Batch{Process: &jaeger.Process{ServiceName: "svc"}}
+		for j := 0; j < 3; j++ {
+			batch.Spans = append(batch.Spans, &jaeger.Span{
+				TraceID:   jaeger.NewTraceID(0, uint64(j/2+1)),
+				SpanID:    jaeger.NewSpanID(uint64(i*3 + j + 1)),
+				StartTime: time.Unix(1700000000, 0),
+				Duration:  time.Millisecond,
+			})
+		}
+		data, err := proto.Marshal(batch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		queued = append(queued, BadgerEntry{Key: "k", Value: data, Seq: int64(i)})
+	}
+
+	runPipeline(t, c, queued, 4)
+
+	checkpoint, err := LoadCheckpoint(config.CheckpointFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkpoint.Entries != entries {
+		t.Errorf("checkpoint entries = %d, want %d", checkpoint.Entries, entries)
+	}
+	if got := c.TotalSpans(); got != 2*entries {
+		t.Errorf("spans written = %d, want %d", got, 2*entries)
+	}
+}
+
+// runPipeline feeds entries through workers, the collector and one
+// background writer, and waits for everything to finish
+func runPipeline(t *testing.T, c *Converter, entries []BadgerEntry, workers int) {
+	t.Helper()
+	writerDone := make(chan struct{})
+	go c.BackgroundWriter(writerDone)
+
+	entryChan := make(chan BadgerEntry, len(entries))
+	resultChan := make(chan *OTLPSpan, 16)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go c.Worker(entryChan, resultChan, &wg)
+	}
+	collectorDone := make(chan struct{})
+	go c.ResultCollector(resultChan, collectorDone)
+
+	for _, entry := range entries {
+		entryChan <- entry
+	}
+	close(entryChan)
+	wg.Wait()
+	close(resultChan)
+	<-collectorDone
+	c.Shutdown()
+	<-writerDone
+}
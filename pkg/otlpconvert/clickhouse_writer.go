@@ -0,0 +1,138 @@
+package otlpconvert
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clickHouseColumns names the TSV columns in order. They follow the
+// otel_traces table of the OpenTelemetry Collector's ClickHouse exporter.
+var clickHouseColumns = []string{
+	"TraceId", "SpanId", "ParentSpanId", "ServiceName", "SpanName", "SpanKind",
+	"Timestamp", "Duration", "StatusCode", "SpanAttributes", "ResourceAttributes",
+}
+
+// clickHouseEscaper escapes values for ClickHouse's TabSeparated format
+var clickHouseEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"\t", `\t`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\x00", `\0`,
+)
+
+// clickHouseTimeLayout is how DateTime64(9) values are written
+const clickHouseTimeLayout = "2006-01-02 15:04:05.000000000"
+
+// WriteClickHouseTSV writes OTLP spans as tab-separated rows with the columns
+// of clickHouseColumns and no header, ready for
+// INSERT INTO otel_traces (...) FORMAT TabSeparated. Timestamp is the UTC
+// start time with nanoseconds, Duration is in nanoseconds, SpanKind and
+// StatusCode use the exporter's names (Server, Error, ...) and the attribute
+// columns are JSON objects of string values.
+func WriteClickHouseTSV(filename string, spans []*OTLPSpan) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	fields := make([]string, len(clickHouseColumns))
+	for _, span := range spans {
+		start, _ := strconv.ParseInt(span.StartTimeUnixNano, 10, 64)
+		end, _ := strconv.ParseInt(span.EndTimeUnixNano, 10, 64)
+
+		spanAttrs, err := clickHouseAttributes(span.Attributes)
+		if err != nil {
+			return err
+		}
+		resourceAttrs, err := clickHouseAttributes(span.Resource)
+		if err != nil {
+			return err
+		}
+
+		fields[0] = span.TraceID
+		fields[1] = span.SpanID
+		fields[2] = span.ParentSpanID
+		fields[3] = spanServiceName(span)
+		fields[4] = span.Name
+		fields[5] = clickHouseEnum(span.Kind, "SPAN_KIND_")
+		fields[6] = time.Unix(0, start).UTC().Format(clickHouseTimeLayout)
+		fields[7] = strconv.FormatInt(end-start, 10)
+		fields[8] = clickHouseEnum(span.Status.Code, "STATUS_CODE_")
+		fields[9] = spanAttrs
+		fields[10] = resourceAttrs
+		for i, field := range fields {
+			if i > 0 {
+				w.WriteByte('\t')
+			}
+			clickHouseEscaper.WriteString(w, field)
+		}
+		w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// clickHouseEnum turns an OTLP enum name such as SPAN_KIND_SERVER into the
+// exporter's form, Server
+func clickHouseEnum(value, prefix string) string {
+	name := strings.ToLower(strings.TrimPrefix(value, prefix))
+	if name == "" {
+		return "Unspecified"
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// clickHouseAttributes encodes attributes as a JSON object of strings, the
+// shape of the exporter's Map(String, String) columns. Arrays and maps are
+// kept as their OTLP JSON.
+func clickHouseAttributes(attrs []Attribute) (string, error) {
+	values := make(map[string]string, len(attrs))
+	for _, attr := range attrs {
+		value, err := attributeText(attr.Value)
+		if err != nil {
+			return "", err
+		}
+		values[attr.Key] = value
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(values); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// attributeText renders an attribute value as plain text
+func attributeText(v AttributeValue) (string, error) {
+	switch {
+	case v.BoolValue != nil:
+		return strconv.FormatBool(*v.BoolValue), nil
+	case v.IntValue != nil:
+		return strconv.FormatInt(*v.IntValue, 10), nil
+	case v.DoubleValue != nil:
+		return strconv.FormatFloat(*v.DoubleValue, 'g', -1, 64), nil
+	case v.BytesValue != "":
+		return v.BytesValue, nil
+	case v.ArrayValue != nil:
+		data, err := json.Marshal(v.ArrayValue)
+		return string(data), err
+	case v.KvlistValue != nil:
+		data, err := json.Marshal(v.KvlistValue)
+		return string(data), err
+	default:
+		return v.StringValue, nil
+	}
+}
@@ -0,0 +1,502 @@
+package otlpconvert
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Config controls how a Converter reads, converts and writes spans
+type Config struct {
+	InputFile          string // file, glob pattern, or comma-separated list of either
+	OutputFile         string // base name, may include directories (created as needed); "-" for stdout
+	MaxEntries         int
+	LimitBatches       int // stop reading once this many batches are flushed (0 = no limit)
+	NumWorkers         int
+	IOWorkers          int // BackgroundWriter goroutines run by the CLI
+	InputWorkers       int // input files the CLI decodes concurrently (1 = one after another)
+	BatchSize          int
+	EntryQueue         int // entryChan capacity (CLI default: BatchSize)
+	ResultQueue        int // resultChan capacity (CLI default: 2 x BatchSize)
+	WriteInterval      int
+	MaxMemoryMB        int           // heap size that forces an early flush (0 = no limit)
+	FlushInterval      time.Duration // flush buffered spans after this long without a flush (0 = 30s)
+	OutputFormat       string        // "arrow", "json", "protobuf", "both", "csv", "clickhouse-tsv" or "http"
+	InputFormat        string        // "badger", "ndjson" or "zipkin"
+	ValueEncoding      string        // "hex", "base64" or "raw"
+	ValueShape         string        // "" / "span" (one span per value) or "spanlist" (jaeger.Batch)
+	Pretty             bool          // indent OTLP JSON output
+	ArrowChunkSize     int           // rows per Arrow record batch (0 = one batch per file)
+	ArrowSchemaVersion int           // ArrowSchemaHexIDs or ArrowSchemaCompactIDs
+	ArrowTimeType      string        // start_time/end_time columns: "" / "none", "int" or "timestamp"
+	ArrowSpanEncoding  string        // otlp_span column: "" / "json" (JSON string) or "proto" (OTLP Span protobuf bytes)
+	ArrowAppend        bool          // append every batch to <output>.arrow instead of batch files
+	WriteRetries       int           // extra attempts for a failed batch file write
+	InputRetries       int           // extra attempts for a failed -input URL request (CLI)
+	PartitionBy        string        // "" (none), "service", "minute", "hour" or "day"
+	GroupBy            string        // OTLP JSON layout: "" / "resource" (one TracesData) or "trace" (one per trace)
+	SchemaURL          string        // schemaUrl of every ResourceSpans and ScopeSpans (empty = omitted)
+	KeepTracesTogether bool          // flush a full buffer only when a new trace starts, so traces are not split
+	OneFilePerTrace    bool          // write <output>.<traceid>.otlp.json per trace instead of batch files
+	MaxTraceFiles      int           // cap on per-trace files (0 = unlimited, only allowed with TraceIDs)
+	Deterministic      bool          // sort traces and spans so identical input gives identical files
+	MetricsAddr        string        // empty disables the metrics server
+	LogFormat          string        // "text" or "json"
+	LogLevel           string        // "debug", "info", "warn" or "error"
+	ProfileParse       bool          // record per-entry parse latency percentiles
+	MaxErrors          int64         // parse errors tolerated before the CLI exits non-zero
+	Benchmark          int           // convert this many synthetic spans in memory instead of reading input
+	Generate           int           // convert and write this many generated spans instead of reading input (CLI)
+	Seed               int64         // random seed for Generate
+	Stats              bool          // summarize the input (CLI -stats) instead of converting it
+	Peek               int           // print the first N converted spans (CLI -peek) instead of converting
+	Follow             bool          // keep reading the last input file as it grows (CLI -follow)
+
+	// OTLP/HTTP export (OutputFormat "http")
+	Endpoint     string            // e.g. https://collector:4318/v1/traces
+	HTTPEncoding string            // "protobuf" or "json"
+	Headers      map[string]string // extra request headers, e.g. Authorization
+	RateLimit    int               // spans per second sent to Endpoint (0 = unlimited)
+
+	// Conversion options
+	SkipInvalidTimestamps bool              // drop spans with a zero/pre-epoch start instead of clamping
+	EndBeforeStart        string            // "" / "clamp" (end = start) or "flag" (keep, add conversion.warning)
+	OTelNativeTags        bool              // map otel.* tags of spans translated from OTLP back to native fields
+	SpanKindDefault       string            // kind of spans without a span.kind tag: "" / "internal", "server", "client", ...
+	MaxSpansPerTrace      int               // spans kept per trace within one buffer (0 = unlimited)
+	MaxAttrsPerSpan       int               // attributes kept per span or event (0 = unlimited)
+	MaxAttrValueLen       int               // bytes kept of each string attribute value (0 = unlimited)
+	MaxEventsPerSpan      int               // events kept per span (0 = unlimited)
+	EventSampling         string            // events kept over MaxEventsPerSpan: "" / "first" or "even"
+	DefaultService        string            // service.name when the process has none (empty = "unknown")
+	ServiceFromTag        string            // tag key to take service.name from before DefaultService
+	DropUnknownService    bool              // drop spans that would get service.name "unknown" instead of keeping them
+	KeepSourceKey         bool              // add the entry key as a jaeger.badger_key span attribute
+	ValidateOTLP          bool              // check converted spans against OTLP invariants and count violations
+	Strict                bool              // with ValidateOTLP, drop invalid spans and fail the run
+	ResourceAttributes    map[string]string // added to every resource, overriding process tags
+	DedupProcesses        bool              // build each distinct process's resource once and share it
+	RenameMapFile         string            // JSON file loaded into RenameMap by the CLI
+	RenameMap             map[string]string // tag key -> attribute key; unmapped keys pass through
+	ProcessMapFile        string            // JSON file loaded into ProcessMap by the CLI
+	ProcessMap            ProcessMap        // ProcessID -> process for spans stored without one
+	NormalizeKeys         string            // unmapped tag keys: "" / "none", "dots" (a_b -> a.b, undotted keys only) or "underscores" (a.b -> a_b)
+	RedactKeys            []string          // attribute values replaced with "[REDACTED]"
+	FlattenJSONTags       bool              // expand string tags holding JSON objects into attributes
+	JSONTagStyle          string            // "dotted" (one attribute per field) or "kvlist" (one kvlistValue)
+	AttrPrecedence        string            // "" / "span" or "resource": which side keeps a key both set in Arrow/CSV rows
+	HashKeys              []string          // attribute values replaced with their SHA-256 hex
+	TraceIDs              []string          // hex trace IDs to keep, leading zeros optional (empty = all)
+	Sample                float64           // fraction of traces kept, chosen by trace ID hash, in (0, 1]; unset keeps all
+	Since                 time.Time         // keep spans starting at or after this (zero = unbounded)
+	Until                 time.Time         // keep spans starting before this (zero = unbounded)
+	Transform             SpanTransform     // library hook applied to every converted span (nil = none)
+
+	CheckpointFile string // empty disables checkpointing
+	Resume         bool   // continue from CheckpointFile
+	SkipEntries    int64  // input entries to skip before queuing (set when resuming)
+	DoneMarker     bool   // write <output>.done once the run succeeds
+}
+
+// Validate rejects settings that would make a run misbehave
+func (c *Config) Validate() error {
+	if c.NumWorkers <= 0 {
+		return fmt.Errorf("-workers must be positive, got %d", c.NumWorkers)
+	}
+	if c.BatchSize <= 0 {
+		return fmt.Errorf("-batch must be positive, got %d", c.BatchSize)
+	}
+	// Queues size the worker channels; zero would leave them unbuffered
+	if c.EntryQueue <= 0 {
+		return fmt.Errorf("-entry-queue must be positive, got %d", c.EntryQueue)
+	}
+	if c.ResultQueue <= 0 {
+		return fmt.Errorf("-result-queue must be positive, got %d", c.ResultQueue)
+	}
+	if c.IOWorkers <= 0 {
+		return fmt.Errorf("-io-workers must be positive, got %d", c.IOWorkers)
+	}
+	if c.InputWorkers <= 0 {
+		return fmt.Errorf("-input-workers must be positive, got %d", c.InputWorkers)
+	}
+	if c.InputWorkers > 1 {
+		// Entries of concurrently read files interleave, so an entry count
+		// does not say which entries were written
+		if c.CheckpointFile != "" || c.Resume {
+			return fmt.Errorf("-input-workers reads files out of order and cannot be combined with -checkpoint or -resume")
+		}
+		if c.Follow {
+			return fmt.Errorf("-input-workers cannot be combined with -follow")
+		}
+	}
+	if c.WriteInterval <= 0 {
+		return fmt.Errorf("-write-interval must be positive, got %d", c.WriteInterval)
+	}
+	if c.FlushInterval <= 0 {
+		return fmt.Errorf("-flush-interval must be positive, got %s", c.FlushInterval)
+	}
+	if c.MaxMemoryMB < 0 {
+		return fmt.Errorf("-max-memory-mb must not be negative, got %d", c.MaxMemoryMB)
+	}
+	if !c.Since.IsZero() && !c.Until.IsZero() && !c.Until.After(c.Since) {
+		return fmt.Errorf("-until (%s) must be after -since (%s)", c.Until.Format(time.RFC3339), c.Since.Format(time.RFC3339))
+	}
+	if c.MaxSpansPerTrace < 0 {
+		return fmt.Errorf("-max-spans-per-trace must not be negative, got %d", c.MaxSpansPerTrace)
+	}
+	for _, id := range c.TraceIDs {
+		if _, err := normalizeTraceID(id); err != nil {
+			return fmt.Errorf("-trace-id: %w", err)
+		}
+	}
+	if c.MaxAttrsPerSpan < 0 {
+		return fmt.Errorf("-max-attrs-per-span must not be negative, got %d", c.MaxAttrsPerSpan)
+	}
+	if c.MaxAttrValueLen < 0 {
+		return fmt.Errorf("-max-attr-value-len must not be negative, got %d", c.MaxAttrValueLen)
+	}
+	if c.MaxEventsPerSpan < 0 {
+		return fmt.Errorf("-max-events-per-span must not be negative, got %d", c.MaxEventsPerSpan)
+	}
+	// A rate of 0 would convert nothing; Config{} leaves Sample unset
+	// instead, which New treats as keeping every trace
+	if c.Sample <= 0 || c.Sample > 1 {
+		return fmt.Errorf("-sample must be greater than 0 and at most 1, got %g", c.Sample)
+	}
+	if c.MaxErrors < 0 {
+		return fmt.Errorf("-max-errors must not be negative, got %d", c.MaxErrors)
+	}
+	if c.WriteRetries < 0 {
+		return fmt.Errorf("-write-retries must not be negative, got %d", c.WriteRetries)
+	}
+	if c.InputRetries < 0 {
+		return fmt.Errorf("-input-retries must not be negative, got %d", c.InputRetries)
+	}
+	switch c.ArrowSchemaVersion {
+	case 0, ArrowSchemaHexIDs, ArrowSchemaCompactIDs:
+	default:
+		return fmt.Errorf("unknown Arrow schema version %d (want %d or %d)", c.ArrowSchemaVersion, ArrowSchemaHexIDs, ArrowSchemaCompactIDs)
+	}
+	if c.ArrowChunkSize < 0 {
+		return fmt.Errorf("-arrow-chunk-size must not be negative, got %d", c.ArrowChunkSize)
+	}
+	if c.MaxEntries < 0 {
+		return fmt.Errorf("-max must not be negative, got %d", c.MaxEntries)
+	}
+	if c.LimitBatches < 0 {
+		return fmt.Errorf("-limit-batches must not be negative, got %d", c.LimitBatches)
+	}
+	if c.LimitBatches > 0 && c.Follow {
+		return fmt.Errorf("-limit-batches cannot be combined with -follow")
+	}
+
+	switch c.OutputFormat {
+	case "arrow", "json", "protobuf", "both", "csv", "clickhouse-tsv":
+	case "http":
+		if err := c.validateEndpoint(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown -format %q (want arrow, json, protobuf, both, csv, clickhouse-tsv, or http)", c.OutputFormat)
+	}
+	if c.DropUnknownService && c.DefaultService != "" && c.DefaultService != "unknown" {
+		return fmt.Errorf("-drop-unknown-service cannot be combined with -default-service %q", c.DefaultService)
+	}
+	if c.Strict && !c.ValidateOTLP {
+		return fmt.Errorf("-strict only applies with -validate-otlp")
+	}
+	if c.RateLimit < 0 {
+		return fmt.Errorf("-rate-limit must not be negative, got %d", c.RateLimit)
+	}
+	if c.RateLimit > 0 && c.OutputFormat != "http" {
+		return fmt.Errorf("-rate-limit only applies to export (-format http)")
+	}
+
+	switch c.PartitionBy {
+	case "", "none", "service", "minute", "hour", "day":
+	default:
+		return fmt.Errorf("unknown -partition-by %q (want none, service, minute, hour, or day)", c.PartitionBy)
+	}
+
+	switch c.ArrowTimeType {
+	case "", "none":
+	case "int", "timestamp":
+		if c.OutputFormat != "arrow" && c.OutputFormat != "both" {
+			return fmt.Errorf("-arrow-time-type only applies to Arrow output (-format arrow or both)")
+		}
+	default:
+		return fmt.Errorf("unknown -arrow-time-type %q (want none, int, or timestamp)", c.ArrowTimeType)
+	}
+
+	if c.SchemaURL != "" {
+		u, err := url.Parse(c.SchemaURL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid -schema-url %q (want an absolute URL such as https://opentelemetry.io/schemas/1.21.0)", c.SchemaURL)
+		}
+		switch c.OutputFormat {
+		case "json", "protobuf", "both", "http":
+		default:
+			return fmt.Errorf("-schema-url only applies to OTLP output (-format json, protobuf, both, or http)")
+		}
+	}
+
+	switch c.NormalizeKeys {
+	case "", "none", "dots", "underscores":
+	default:
+		return fmt.Errorf("unknown -normalize-attribute-keys %q (want none, dots, or underscores)", c.NormalizeKeys)
+	}
+
+	switch c.ArrowSpanEncoding {
+	case "", "json":
+	case "proto":
+		if c.OutputFormat != "arrow" && c.OutputFormat != "both" {
+			return fmt.Errorf("-arrow-span-encoding only applies to Arrow output (-format arrow or both)")
+		}
+	default:
+		return fmt.Errorf("unknown -arrow-span-encoding %q (want json or proto)", c.ArrowSpanEncoding)
+	}
+
+	switch c.GroupBy {
+	case "", "resource":
+	case "trace":
+		if c.OutputFormat != "json" && c.OutputFormat != "both" {
+			return fmt.Errorf("-group-by trace only applies to OTLP JSON output (-format json or both)")
+		}
+	default:
+		return fmt.Errorf("unknown -group-by %q (want resource or trace)", c.GroupBy)
+	}
+
+	if c.ArrowAppend && c.OutputFormat != "arrow" && c.OutputFormat != "both" {
+		return fmt.Errorf("-arrow-append only applies to Arrow output (-format arrow or both)")
+	}
+	if c.OneFilePerTrace {
+		if c.OutputFormat != "json" {
+			return fmt.Errorf("-one-file-per-trace requires -format json")
+		}
+		if c.GroupBy == "trace" {
+			return fmt.Errorf("-one-file-per-trace cannot be combined with -group-by trace")
+		}
+		if c.Resume {
+			return fmt.Errorf("-one-file-per-trace cannot be combined with -resume")
+		}
+		if c.MaxTraceFiles == 0 && len(c.TraceIDs) == 0 {
+			return fmt.Errorf("-one-file-per-trace needs -trace-id or a positive -max-trace-files")
+		}
+	}
+	if c.MaxTraceFiles < 0 {
+		return fmt.Errorf("-max-trace-files must not be negative, got %d", c.MaxTraceFiles)
+	}
+
+	if _, ok := spanKinds[c.SpanKindDefault]; c.SpanKindDefault != "" && !ok {
+		return fmt.Errorf("unknown -span-kind-default %q (want server, client, producer, consumer, or internal)", c.SpanKindDefault)
+	}
+
+	switch c.EndBeforeStart {
+	case "", "clamp", "flag":
+	default:
+		return fmt.Errorf("unknown -end-before-start %q (want clamp or flag)", c.EndBeforeStart)
+	}
+
+	switch c.EventSampling {
+	case "", "first", "even":
+	default:
+		return fmt.Errorf("unknown -event-sampling %q (want first or even)", c.EventSampling)
+	}
+
+	switch c.JSONTagStyle {
+	case "", "dotted", "kvlist":
+	default:
+		return fmt.Errorf("unknown -json-tag-style %q (want dotted or kvlist)", c.JSONTagStyle)
+	}
+
+	switch c.AttrPrecedence {
+	case "", "span", "resource":
+	default:
+		return fmt.Errorf("unknown -attr-precedence %q (want span or resource)", c.AttrPrecedence)
+	}
+
+	switch c.InputFormat {
+	case "badger", "ndjson", "zipkin":
+	default:
+		return fmt.Errorf("unknown -input-format %q (want badger, ndjson, or zipkin)", c.InputFormat)
+	}
+	if c.InputFormat == "zipkin" && c.Benchmark > 0 {
+		return fmt.Errorf("-input-format zipkin and -benchmark cannot be combined")
+	}
+	if c.InputFormat == "zipkin" && c.Generate > 0 {
+		return fmt.Errorf("-input-format zipkin and -generate cannot be combined")
+	}
+	if c.InputFormat == "zipkin" && c.ProcessMapFile != "" {
+		return fmt.Errorf("-process-map only applies to Jaeger input, not -input-format zipkin")
+	}
+
+	switch c.ValueEncoding {
+	case "hex", "base64", "raw":
+	default:
+		return fmt.Errorf("unknown -value-encoding %q (want hex, base64, or raw)", c.ValueEncoding)
+	}
+
+	switch c.ValueShape {
+	case "", "span", "spanlist":
+	default:
+		return fmt.Errorf("unknown -value-shape %q (want span or spanlist)", c.ValueShape)
+	}
+
+	switch c.LogFormat {
+	case "text", "json":
+	default:
+		return fmt.Errorf("unknown -log-format %q (want text or json)", c.LogFormat)
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		return fmt.Errorf("unknown -log-level %q (want debug, info, warn, or error)", c.LogLevel)
+	}
+
+	if c.Benchmark < 0 {
+		return fmt.Errorf("-benchmark must not be negative, got %d", c.Benchmark)
+	}
+	if c.Stats && c.Benchmark > 0 {
+		return fmt.Errorf("-stats and -benchmark cannot be combined")
+	}
+	if c.Peek < 0 {
+		return fmt.Errorf("-peek must not be negative, got %d", c.Peek)
+	}
+	if c.Peek > 0 && (c.Stats || c.Benchmark > 0) {
+		return fmt.Errorf("-peek cannot be combined with -stats or -benchmark")
+	}
+	if c.Follow && (c.Stats || c.Peek > 0 || c.Benchmark > 0) {
+		return fmt.Errorf("-follow cannot be combined with -stats, -peek or -benchmark")
+	}
+	if c.Generate < 0 {
+		return fmt.Errorf("-generate must not be negative, got %d", c.Generate)
+	}
+	if c.Generate > 0 && (c.Stats || c.Peek > 0 || c.Benchmark > 0 || c.Follow) {
+		return fmt.Errorf("-generate cannot be combined with -stats, -peek, -benchmark or -follow")
+	}
+	// Benchmark and generate modes make their own entries and read no input
+	if c.Benchmark == 0 && c.Generate == 0 {
+		if c.InputFile == "" {
+			return fmt.Errorf("-input is required")
+		}
+		files, err := c.InputFiles()
+		if err != nil {
+			return err
+		}
+		if c.Follow && IsInputURL(files[len(files)-1]) {
+			return fmt.Errorf("-follow only applies to a local input file, not a URL")
+		}
+	}
+
+	if IsS3URL(c.OutputFile) {
+		if _, _, err := ParseS3URL(c.OutputFile); err != nil {
+			return fmt.Errorf("-output: %w", err)
+		}
+		if _, err := NewS3ClientFromEnv(); err != nil {
+			return fmt.Errorf("-output: %w", err)
+		}
+		// Both read back earlier output to extend it
+		if c.ArrowAppend || c.OneFilePerTrace {
+			return fmt.Errorf("an s3:// -output cannot be combined with -arrow-append or -one-file-per-trace")
+		}
+	} else if strings.Contains(c.OutputFile, "://") {
+		// Would otherwise be taken as a local directory named "https:"
+		return fmt.Errorf("-output %q: only local paths, s3://bucket/prefix and - are supported", c.OutputFile)
+	}
+	if strings.Contains(c.CheckpointFile, "://") {
+		return fmt.Errorf("-checkpoint must be a local file, got %q", c.CheckpointFile)
+	}
+
+	if c.WritesToStdout() {
+		if c.OutputFormat != "json" {
+			return fmt.Errorf("-output - only applies to OTLP JSON output (-format json)")
+		}
+		if c.OneFilePerTrace || (c.PartitionBy != "" && c.PartitionBy != "none") {
+			return fmt.Errorf("-output - writes a single export and cannot be combined with -one-file-per-trace or -partition-by")
+		}
+		if c.MaxMemoryMB > 0 {
+			return fmt.Errorf("-output - holds every span until the end and cannot be combined with -max-memory-mb")
+		}
+	}
+
+	if c.Resume && c.CheckpointFile == "" {
+		return fmt.Errorf("-resume requires a checkpoint file")
+	}
+	if c.DoneMarker && (c.WritesToStdout() || c.OutputFormat == "http") {
+		return fmt.Errorf("-done-marker only applies to output files, not -output - or -format http")
+	}
+
+	return nil
+}
+
+// IsInputURL reports whether an element of InputFiles is an http://,
+// https:// or s3:// URL to stream rather than a local path
+func IsInputURL(input string) bool {
+	return strings.HasPrefix(input, "http://") || strings.HasPrefix(input, "https://") || IsS3URL(input)
+}
+
+// WritesToStdout reports whether output goes to stdout (OutputFile "-") as a
+// single OTLP JSON export written once all input is converted
+func (c *Config) WritesToStdout() bool {
+	return c.OutputFile == "-"
+}
+
+// validateEndpoint checks the OTLP/HTTP settings used by -format http
+func (c *Config) validateEndpoint() error {
+	if c.Endpoint == "" {
+		return fmt.Errorf("-format http requires -endpoint")
+	}
+	u, err := url.Parse(c.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid -endpoint %q (want an http:// or https:// URL)", c.Endpoint)
+	}
+	switch c.HTTPEncoding {
+	case "protobuf", "json":
+	default:
+		return fmt.Errorf("unknown -http-encoding %q (want protobuf or json)", c.HTTPEncoding)
+	}
+	return nil
+}
+
+// InputFiles expands InputFile into the files to read, in order. Each
+// comma-separated element is a path, a glob pattern or an http(s) or s3 URL;
+// glob matches are sorted, and a pattern that matches nothing is an error.
+func (c *Config) InputFiles() ([]string, error) {
+	var files []string
+	for _, pattern := range strings.Split(c.InputFile, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if IsS3URL(pattern) {
+			if _, _, err := ParseS3URL(pattern); err != nil {
+				return nil, fmt.Errorf("input: %w", err)
+			}
+		}
+		if IsInputURL(pattern) {
+			// Fetched as is; a URL is never a glob
+			files = append(files, pattern)
+			continue
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("input pattern %q: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			// Not a glob (or no matches): report the path the way os.Stat does
+			if _, err := os.Stat(pattern); err != nil {
+				return nil, fmt.Errorf("input file: %w", err)
+			}
+			matches = []string{pattern}
+		}
+		files = append(files, matches...)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("-input is required")
+	}
+	return files, nil
+}
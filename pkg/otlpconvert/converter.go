@@ -0,0 +1,1602 @@
+// Package otlpconvert converts Jaeger spans into OTLP spans and writes them as
+// Arrow or OTLP JSON batch files. It backs the otlp-converter CLI and can be
+// embedded directly by services that already hold Jaeger spans in memory.
+package otlpconvert
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	jaeger "github.com/jaegertracing/jaeger/model"
+)
+
+// Version is the converter version recorded in output metadata. Release
+// builds set it with -ldflags "-X otlp-converter-go/pkg/otlpconvert.Version=...".
+var Version = "dev"
+
+// BadgerExport is the top-level structure of a BadgerDB export file
+type BadgerExport struct {
+	Entries []BadgerEntry `json:"entries"`
+}
+
+// BadgerEntry is a single exported key/value pair holding a Jaeger span
+type BadgerEntry struct {
+	Key   string     `json:"key"`
+	Value EntryValue `json:"value"` // protobuf, encoded per Config.ValueEncoding
+
+	// Seq is the entry's position among the entries queued this run,
+	// counting from 0. With Config.CheckpointFile set, entries must be
+	// queued with consecutive Seq values so the checkpoint can tell which
+	// input is fully written.
+	Seq int64 `json:"-"`
+}
+
+// Converter converts Jaeger spans to OTLP and writes them in batches
+type Converter struct {
+	config     *Config
+	buffers    map[string]*traceBuffer // keyed by time window
+	tracesLock sync.Mutex
+
+	// Traces and services collected this run, across flushes; guarded by
+	// tracesLock
+	seenTraces   map[[16]byte]struct{}
+	serviceSpans map[string]int64 // spans collected per service.name
+
+	writeChan  chan writeBatch
+	totalSpans int
+	batchCount int
+	statsLock  sync.Mutex
+
+	// Updated by workers on every entry, so kept lock-free
+	entriesProcessed    atomic.Int64
+	parseErrors         atomic.Int64
+	backpressureEvents  atomic.Int64
+	invalidTimestamps   atomic.Int64
+	endBeforeStart      atomic.Int64
+	unknownTagTypes     atomic.Int64
+	traceLimitDrops     atomic.Int64
+	outsideTimeRange    atomic.Int64
+	lostBatches         atomic.Int64
+	sampledOut          atomic.Int64
+	traceIDFiltered     atomic.Int64
+	traceFileDrops      atomic.Int64
+	malformedIDs        atomic.Int64
+	attrConflicts       atomic.Int64
+	maxTraceSpans       atomic.Int64
+	memoryFlushes       atomic.Int64
+	otelNativeSpans     atomic.Int64
+	unknownServiceDrops atomic.Int64
+	unresolvedProcesses atomic.Int64
+	invalidSpans        atomic.Int64
+	violations          [numViolations]atomic.Int64 // per kind, with Config.ValidateOTLP
+
+	parseLatency    *latencyHistogram // nil unless Config.ProfileParse
+	processes       *processCache     // nil unless Config.DedupProcesses
+	limiter         *rateLimiter      // nil unless Config.RateLimit
+	progress        *entryProgress    // nil unless Config.CheckpointFile
+	s3              *S3Client         // nil unless OutputFile is an s3:// URL
+	s3Err           error             // why s3 could not be set up
+	sampleThreshold uint64            // from Config.Sample; 0 keeps every trace
+	defaultKind     string            // OTLP kind for Config.SpanKindDefault
+	traceIDs        map[string]bool   // Config.TraceIDs, normalized; nil keeps every trace
+	resourceAttrs   []Attribute       // Config.ResourceAttributes, sorted by key
+	redactKeys      map[string]bool   // Config.RedactKeys
+	hashKeys        map[string]bool   // Config.HashKeys
+
+	arrowAppends    map[string]*arrowAppendFile // ArrowAppend files open this run
+	arrowAppendLock sync.Mutex                  // guards arrowAppends
+	activeWriters   atomic.Int32                // running BackgroundWriters
+
+	traceFiles     map[string]bool // per-trace files written this run (OneFilePerTrace)
+	traceFilesLock sync.Mutex
+
+	entryOffset    int64         // entries consumed by a previous run when resuming
+	flushSeq       int64         // next writeBatch.seq; only touched by the collector
+	stopInput      chan struct{} // closed once no more input is wanted: batch limit or full disk
+	stopOnce       sync.Once
+	batchLimitHit  atomic.Bool
+	diskFull       atomic.Bool
+	checkpoint     Checkpoint
+	checkpointSeq  int64           // next flush the checkpoint is waiting for
+	pendingFlushes map[int64]int64 // seq -> entries of batches written ahead of checkpointSeq
+	checkpointLock sync.Mutex
+}
+
+// traceBuffer holds the spans collected for one time window ("" when output
+// is not partitioned by time), grouped by trace ID
+type traceBuffer struct {
+	traces map[string][]*OTLPSpan
+	spans  int
+}
+
+// writeBatch is a set of traces cut by a flush, with the number of input
+// entries consumed when it was cut
+type writeBatch struct {
+	traces  map[string][]*OTLPSpan
+	window  string
+	entries int64
+	seq     int64 // flush order, so the checkpoint can wait for earlier batches
+}
+
+// New creates a Converter for the given configuration
+func New(config Config) *Converter {
+	c := &Converter{
+		config:       &config,
+		buffers:      make(map[string]*traceBuffer),
+		seenTraces:   make(map[[16]byte]struct{}),
+		serviceSpans: make(map[string]int64),
+		writeChan:    make(chan writeBatch, 3),
+		totalSpans:   0,
+		batchCount:   0,
+	}
+	if config.ProfileParse {
+		c.parseLatency = &latencyHistogram{}
+	}
+	if config.OneFilePerTrace {
+		c.traceFiles = make(map[string]bool)
+	}
+	if config.DedupProcesses {
+		c.processes = &processCache{resources: make(map[string][]Attribute)}
+	}
+	if config.RateLimit > 0 {
+		c.limiter = newRateLimiter(config.RateLimit)
+	}
+	if config.CheckpointFile != "" {
+		c.progress = newEntryProgress()
+	}
+	if IsS3URL(config.OutputFile) {
+		// Validate checks the credentials; a library caller without them
+		// sees the error on every write
+		c.s3, c.s3Err = NewS3ClientFromEnv()
+	}
+	c.stopInput = make(chan struct{})
+	c.sampleThreshold = sampleThreshold(config.Sample)
+	c.defaultKind = otlpSpanKind(config.SpanKindDefault)
+	c.traceIDs = traceIDSet(config.TraceIDs)
+	c.resourceAttrs = sortedAttributes(config.ResourceAttributes)
+	c.redactKeys = keySet(config.RedactKeys)
+	c.hashKeys = keySet(config.HashKeys)
+	return c
+}
+
+// SpanTransform post-processes a converted span, for rules too specific for
+// the built-in options. It runs last in conversion, after tags, process and
+// logs have been mapped, and may rewrite the name, status or attributes of
+// out. It is called concurrently from all workers and must not keep out or
+// its slices after returning, as spans are recycled once written. out.Resource
+// must be treated as read-only when DedupProcesses is set, since it is shared
+// with other spans of the same process.
+type SpanTransform func(in *jaeger.Span, out *OTLPSpan)
+
+// ConvertJaegerSpan converts a single Jaeger span to OTLP using default
+// settings. It returns nil if the span has a zero trace or span ID, or is
+// otherwise rejected by the converter settings.
+func ConvertJaegerSpan(span *jaeger.Span) *OTLPSpan {
+	return New(Config{}).ConvertJaegerSpan(span)
+}
+
+// ConvertJaegerSpan converts a single Jaeger span to OTLP using the
+// converter's settings. A span with only a ProcessID is resolved through
+// Config.ProcessMap, as entries are. It returns nil if the span has a zero
+// trace or span ID, or is otherwise rejected by the converter settings.
+func (c *Converter) ConvertJaegerSpan(span *jaeger.Span) *OTLPSpan {
+	return c.convertJaegerToOTLP(c.withProcess(span), "", false)
+}
+
+// ConvertEntry decodes an entry and converts its span(s) the way the workers
+// do, before trace ID filtering and sampling. Rejected spans are left out.
+func (c *Converter) ConvertEntry(entry BadgerEntry) []*OTLPSpan {
+	return c.parseEntry(entry)
+}
+
+// Worker parses entries from entryChan and sends converted spans to resultChan
+func (c *Converter) Worker(entryChan <-chan BadgerEntry, resultChan chan<- *OTLPSpan, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for entry := range entryChan {
+		c.entriesProcessed.Add(1)
+		var spans []*OTLPSpan
+		if c.parseLatency != nil {
+			start := time.Now()
+			spans = c.parseEntry(entry)
+			c.parseLatency.record(time.Since(start))
+		} else {
+			spans = c.parseEntry(entry)
+		}
+		emitted := 0
+		for _, span := range spans {
+			if !c.wantedTrace(span) {
+				c.traceIDFiltered.Add(1)
+				releaseSpan(span)
+				continue
+			}
+			if !c.sampled(span) {
+				c.sampledOut.Add(1)
+				releaseSpan(span)
+				continue
+			}
+			if c.config.ValidateOTLP && !c.validateSpan(span) && c.config.Strict {
+				releaseSpan(span)
+				continue
+			}
+			span.entrySeq = entry.Seq
+			resultChan <- span
+			emitted++
+		}
+		if c.progress != nil {
+			c.progress.parsed(entry.Seq, emitted)
+		}
+	}
+}
+
+// isZeroID checks if a byte array contains all zeros
+func isZeroID(idBytes []byte) bool {
+	for _, b := range idBytes {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// parseEntry decodes an entry and converts the span, or every span of a
+// batch with -value-shape spanlist. Rejected spans are left out.
+func (c *Converter) parseEntry(entry BadgerEntry) []*OTLPSpan {
+	// Decode value (hex, base64 or raw)
+	valueBytes, err := c.decodeValue(entry.Value)
+	if err != nil {
+		c.parseErrors.Add(1)
+		return nil
+	}
+
+	// Parse Jaeger protobuf span(s)
+	jaegerSpans, err := c.unmarshalSpans(valueBytes)
+	if err != nil {
+		c.parseErrors.Add(1)
+		return nil
+	}
+
+	zipkin := c.config.InputFormat == "zipkin"
+	var spans []*OTLPSpan
+	for _, jaegerSpan := range jaegerSpans {
+		if otlpSpan := c.parseSpan(jaegerSpan, entry.Key, zipkin); otlpSpan != nil {
+			spans = append(spans, otlpSpan)
+		}
+	}
+	return spans
+}
+
+// parseSpan validates a parsed Jaeger span and converts it. sourceKey is the
+// key of the entry it was read from, if any; zipkin is set for spans decoded
+// from Zipkin.
+func (c *Converter) parseSpan(jaegerSpan *jaeger.Span, sourceKey string, zipkin bool) *OTLPSpan {
+	// Validate TraceID and SpanID are not zero before conversion
+	traceIDBytes, spanIDBytes, err := marshalIDs(jaegerSpan)
+	if err != nil {
+		c.malformedIDs.Add(1)
+		return nil
+	}
+
+	if isZeroID(traceIDBytes[:]) || isZeroID(spanIDBytes[:]) {
+		// Skip invalid spans with zero IDs
+		return nil
+	}
+
+	if !c.inTimeRange(jaegerSpan.StartTime) {
+		c.outsideTimeRange.Add(1)
+		return nil
+	}
+
+	if c.config.DropUnknownService && c.unknownService(jaegerSpan) {
+		c.unknownServiceDrops.Add(1)
+		return nil
+	}
+
+	// Convert to OTLP
+	return c.convertJaegerToOTLP(jaegerSpan, sourceKey, zipkin)
+}
+
+// traceStateKey is the span tag carrying the W3C tracestate header, copied to
+// OTLPSpan.TraceState
+const traceStateKey = "w3c.tracestate"
+
+// sourceKeyKey carries the BadgerEntry.Key a span was read from, with
+// Config.KeepSourceKey
+const sourceKeyKey = "jaeger.badger_key"
+
+// convertJaegerToOTLP converts a span decoded from Jaeger, or from Zipkin when
+// zipkin is set, which only changes the Jaeger-specific markers
+func (c *Converter) convertJaegerToOTLP(jaegerSpan *jaeger.Span, sourceKey string, zipkin bool) *OTLPSpan {
+	// Convert trace ID and span ID to hex strings
+	traceIDBytes, spanIDBytes, err := marshalIDs(jaegerSpan)
+	if err != nil {
+		c.malformedIDs.Add(1)
+		return nil
+	}
+
+	// Validate IDs are not zero (should have been checked in parseEntry, but double-check here)
+	traceID, ok := formatTraceID(traceIDBytes)
+	if !ok {
+		return nil
+	}
+	spanID, ok := formatSpanID(spanIDBytes)
+	if !ok {
+		return nil
+	}
+
+	startTime, endTime, ok := c.spanTimes(jaegerSpan)
+	if !ok {
+		return nil
+	}
+
+	// A negative or huge Duration (the end overflowing int64 nanoseconds)
+	// yields an end before the start
+	endBeforeStart := endTime < startTime
+	if endBeforeStart {
+		c.endBeforeStart.Add(1)
+		if c.config.EndBeforeStart != "flag" {
+			endTime = startTime
+		}
+	}
+
+	// Pooled span: Attributes, Events and Links come back empty but may
+	// have capacity left from an earlier span
+	otlp := getSpan()
+	otlp.TraceID = traceID
+	otlp.SpanID = spanID
+	otlp.TraceIDBytes = traceIDBytes
+	otlp.SpanIDBytes = spanIDBytes
+	// The name is always the Jaeger operation name. No tag overrides it,
+	// including otel.library.* and kind-specific ones like peer.service or
+	// rpc.method; those are kept verbatim as attributes.
+	otlp.Name = jaegerSpan.OperationName
+	otlp.Kind = c.defaultKind
+	otlp.StartTimeUnixNano = strconv.FormatInt(startTime, 10)
+	otlp.EndTimeUnixNano = strconv.FormatInt(endTime, 10)
+	// zipkinTime leaves the start zero when the Zipkin span had no timestamp
+	otlp.missingTimestamp = zipkin && jaegerSpan.StartTime.IsZero()
+	otlp.Status = Status{
+		Code: "STATUS_CODE_UNSET",
+	}
+	otlp.TraceFlags = traceFlags(jaegerSpan.Flags)
+
+	// Process references (parent span and links)
+	if len(jaegerSpan.References) > 0 {
+		for i := range jaegerSpan.References {
+			ref := &jaegerSpan.References[i]
+			refTraceIDBytes, err := marshalTraceID(&ref.TraceID)
+			if err != nil {
+				c.malformedIDs.Add(1)
+				releaseSpan(otlp)
+				return nil
+			}
+			refSpanIDBytes, err := marshalSpanID(&ref.SpanID)
+			if err != nil {
+				c.malformedIDs.Add(1)
+				releaseSpan(otlp)
+				return nil
+			}
+
+			// A zero reference ID points at nothing: a zero parent leaves
+			// the span a root, a zero link is dropped
+			refSpanID, ok := formatSpanID(refSpanIDBytes)
+			if !ok {
+				if ref.RefType != jaeger.SpanRefType_CHILD_OF {
+					otlp.DroppedLinksCount++
+				}
+				continue
+			}
+			if ref.RefType == jaeger.SpanRefType_CHILD_OF {
+				// Set as parent span ID
+				otlp.ParentSpanID = refSpanID
+			} else {
+				// Add as link (FOLLOWS_FROM, etc.)
+				refTraceID, ok := formatTraceID(refTraceIDBytes)
+				if !ok {
+					otlp.DroppedLinksCount++
+					continue
+				}
+				link := Link{
+					TraceID:    refTraceID,
+					SpanID:     refSpanID,
+					Attributes: make([]Attribute, 0),
+				}
+				otlp.Links = append(otlp.Links, link)
+			}
+		}
+	}
+
+	// Spans translated from OTLP carry their native fields as otel.* tags;
+	// with OTelNativeTags those are mapped back instead of kept as attributes
+	otelOrigin := c.config.OTelNativeTags && hasOTelMarkers(jaegerSpan.Tags)
+	var otelStatus Status
+
+	// Convert tags to attributes
+	for _, tag := range jaegerSpan.Tags {
+		attr := c.convertTag(tag)
+		if otelOrigin && applyNativeTag(otlp, &otelStatus, tag, attr) {
+			continue
+		}
+		otlp.Attributes = c.appendTagAttributes(otlp.Attributes, tag, attr)
+
+		// Check for span.kind
+		if tag.Key == "span.kind" {
+			otlp.Kind = otlpSpanKind(tag.VStr)
+		}
+
+		// W3C trace context propagated by the client; taken from the
+		// attribute, like error.message below
+		if tag.Key == traceStateKey {
+			otlp.TraceState = attr.Value.StringValue
+		}
+
+		// Check for error tags and set status
+		if tag.Key == "error" {
+			if tag.VBool || tag.VStr == "true" {
+				otlp.Status.Code = "STATUS_CODE_ERROR"
+			}
+		} else if tag.Key == "error.message" {
+			// Taken from the attribute so a redacted or hashed message stays so
+			otlp.Status.Message = attr.Value.StringValue
+			otlp.Status.Code = "STATUS_CODE_ERROR"
+		} else if tag.Key == "error.type" && otlp.Status.Code == "STATUS_CODE_UNSET" {
+			// If error.type exists, mark as error
+			otlp.Status.Code = "STATUS_CODE_ERROR"
+		}
+	}
+	if otelOrigin {
+		c.otelNativeSpans.Add(1)
+		if otelStatus.Code != "" {
+			otlp.Status.Code = otelStatus.Code
+		}
+		if otelStatus.Message != "" {
+			otlp.Status.Message = otelStatus.Message
+		}
+	}
+
+	// A zero high word means the ID was a 64-bit Jaeger trace ID, padded to
+	// 128 bits above; flag it so consumers can match it against systems that
+	// still use the short form. Zipkin spans keep their IDs as given.
+	if jaegerSpan.TraceID.High == 0 && !zipkin {
+		otlp.Attributes = append(otlp.Attributes, Attribute{
+			Key:   traceID64BitKey,
+			Value: AttributeValue{BoolValue: &trueValue},
+		})
+	}
+	// The debug (force-sampled) flag has no OTLP equivalent
+	if jaegerSpan.Flags.IsDebug() {
+		otlp.Attributes = append(otlp.Attributes, Attribute{
+			Key:   debugFlagKey,
+			Value: AttributeValue{BoolValue: &trueValue},
+		})
+	}
+	if c.config.KeepSourceKey && sourceKey != "" {
+		otlp.Attributes = append(otlp.Attributes, Attribute{
+			Key:   sourceKeyKey,
+			Value: AttributeValue{StringValue: sourceKey},
+		})
+	}
+	if endBeforeStart && c.config.EndBeforeStart == "flag" {
+		otlp.Attributes = append(otlp.Attributes, Attribute{
+			Key:   "conversion.warning",
+			Value: AttributeValue{StringValue: "end time before start time"},
+		})
+	}
+	otlp.Attributes = collapseRepeatedKeys(otlp.Attributes)
+	otlp.Attributes, otlp.DroppedAttributesCount = c.applyAttributeLimits(otlp.Attributes)
+
+	// Convert process to resource attributes, shared between spans of the
+	// same process when DedupProcesses is set
+	if resource, ok := c.cachedResource(jaegerSpan); ok {
+		otlp.Resource = resource
+		otlp.sharedResource = true
+	} else {
+		otlp.Resource = c.buildResource(otlp.Resource, jaegerSpan)
+	}
+
+	// Convert logs to events, only those kept by MaxEventsPerSpan
+	logs, droppedEvents := c.applyEventLimit(jaegerSpan.Logs)
+	otlp.DroppedEventsCount = droppedEvents
+	for _, log := range logs {
+		event := Event{
+			TimeUnixNano: fmt.Sprintf("%d", log.Timestamp.UnixNano()),
+			Name:         "log",
+			Attributes:   make([]Attribute, 0),
+		}
+
+		for _, field := range log.Fields {
+			attr := c.convertTag(field)
+			event.Attributes = c.appendTagAttributes(event.Attributes, field, attr)
+
+			// Use "event" field as event name if present
+			if field.Key == "event" {
+				event.Name = field.VStr
+			}
+		}
+		event.Attributes = collapseRepeatedKeys(event.Attributes)
+		event.Attributes, event.DroppedAttributesCount = c.applyAttributeLimits(event.Attributes)
+
+		otlp.Events = append(otlp.Events, event)
+	}
+
+	// Jaeger logs may be out of order, OTLP consumers expect chronological events
+	sortEventsByTime(otlp.Events)
+
+	if c.config.Transform != nil {
+		c.config.Transform(jaegerSpan, otlp)
+	}
+
+	return otlp
+}
+
+// spanTimes returns the span start and end in Unix nanoseconds. A zero or
+// pre-epoch start time would produce garbage nanos, so it is counted as
+// invalid and either drops the span (ok is false) or is clamped to the epoch
+// while keeping the span duration.
+func (c *Converter) spanTimes(span *jaeger.Span) (start, end int64, ok bool) {
+	if span.StartTime.IsZero() || span.StartTime.Before(time.Unix(0, 0)) {
+		c.invalidTimestamps.Add(1)
+		if c.config.SkipInvalidTimestamps {
+			return 0, 0, false
+		}
+		if span.Duration < 0 {
+			return 0, 0, true
+		}
+		return 0, int64(span.Duration), true
+	}
+
+	start = span.StartTime.UnixNano()
+	end = span.StartTime.Add(span.Duration).UnixNano()
+	return start, end, true
+}
+
+// inTimeRange reports whether a span start falls in [Since, Until); a zero
+// bound leaves that side open
+func (c *Converter) inTimeRange(start time.Time) bool {
+	if !c.config.Since.IsZero() && start.Before(c.config.Since) {
+		return false
+	}
+	if !c.config.Until.IsZero() && !start.Before(c.config.Until) {
+		return false
+	}
+	return true
+}
+
+// debugFlagKey marks spans with Jaeger's debug flag set
+const debugFlagKey = "jaeger.debug"
+
+// traceFlags maps Jaeger flags to OTLP trace flags. Only the sampled bit has
+// an OTLP equivalent; the debug bit is kept as a debugFlagKey attribute
+// instead, and other Jaeger-internal bits are not carried over.
+func traceFlags(flags jaeger.Flags) string {
+	if flags.IsSampled() {
+		return "01"
+	}
+	return "00"
+}
+
+// sortEventsByTime orders events by timestamp. TimeUnixNano is a decimal
+// string, so it is compared numerically rather than lexicographically.
+func sortEventsByTime(events []Event) {
+	if len(events) < 2 {
+		return
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		ti, _ := strconv.ParseInt(events[i].TimeUnixNano, 10, 64)
+		tj, _ := strconv.ParseInt(events[j].TimeUnixNano, 10, 64)
+		return ti < tj
+	})
+}
+
+func (c *Converter) convertTag(tag jaeger.KeyValue) Attribute {
+	attr := Attribute{
+		Key: c.attributeKey(tag.Key),
+	}
+
+	// Scalars are copied before taking their address: a pointer into tag
+	// would keep the whole KeyValue, including VBinary, alive with the span
+	switch tag.VType {
+	case jaeger.ValueType_STRING:
+		attr.Value = AttributeValue{StringValue: tag.VStr}
+	case jaeger.ValueType_BOOL:
+		value := tag.VBool
+		attr.Value = AttributeValue{BoolValue: &value}
+	case jaeger.ValueType_INT64:
+		value := tag.VInt64
+		attr.Value = AttributeValue{IntValue: &value}
+	case jaeger.ValueType_FLOAT64:
+		value := tag.VFloat64
+		attr.Value = AttributeValue{DoubleValue: &value}
+	case jaeger.ValueType_BINARY:
+		// OTLP JSON encodes bytesValue as base64
+		attr.Value = AttributeValue{BytesValue: base64.StdEncoding.EncodeToString(tag.VBinary)}
+	default:
+		c.unknownTagTypes.Add(1)
+		attr.Value = AttributeValue{StringValue: tag.VStr}
+	}
+
+	if c.redactKeys != nil || c.hashKeys != nil {
+		c.scrubValue(tag, &attr)
+	}
+
+	return attr
+}
+
+// ConvertToOTLPJSON converts spans with default settings and returns the
+// OTLP JSON TracesData that -format json would write for them, without
+// touching the filesystem. It is meant for golden-file tests of code that
+// consumes the converter's output.
+func ConvertToOTLPJSON(spans []*jaeger.Span) ([]byte, error) {
+	return New(Config{}).ConvertToOTLPJSON(spans)
+}
+
+// ConvertToOTLPJSON converts spans using the converter's settings and returns
+// the OTLP JSON TracesData that a batch file holding exactly these spans
+// would contain, grouped by resource. Traces and their spans keep the order
+// they first appear in spans (sorted instead with Config.Deterministic), so
+// the output is stable. Spans with only a ProcessID are resolved through
+// Config.ProcessMap. Spans rejected by the converter settings are left out.
+func (c *Converter) ConvertToOTLPJSON(spans []*jaeger.Span) ([]byte, error) {
+	traces := make(map[string][]*OTLPSpan)
+	var order []string
+	for _, span := range spans {
+		otlpSpan := c.convertJaegerToOTLP(c.withProcess(span), "", false)
+		if otlpSpan == nil {
+			continue
+		}
+		if _, ok := traces[otlpSpan.TraceID]; !ok {
+			order = append(order, otlpSpan.TraceID)
+		}
+		traces[otlpSpan.TraceID] = append(traces[otlpSpan.TraceID], otlpSpan)
+	}
+	if c.config.Deterministic {
+		sortTraceSpans(traces)
+		sort.Strings(order)
+	}
+
+	otlpExport, _ := buildOTLPExport(traces, order, c.config.SchemaURL)
+	var buf bytes.Buffer
+	if err := c.encodeOTLPJSON(&buf, []OTLPExport{otlpExport}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// defaultFlushInterval is the idle flush interval when Config.FlushInterval
+// is unset
+const defaultFlushInterval = 30 * time.Second
+
+// ResultCollector groups converted spans by trace (and time window when
+// partitioning by time) and flushes each window to the writer once it holds
+// WriteInterval spans. Everything buffered is flushed once FlushInterval
+// passes without a flush, and early when the heap exceeds MaxMemoryMB. With
+// KeepTracesTogether a full window is only flushed when a span of a trace it
+// does not hold yet arrives, so the traces it holds are not split, and idle
+// flushes are skipped. It closes done once resultChan is drained.
+func (c *Converter) ResultCollector(resultChan <-chan *OTLPSpan, done chan<- struct{}) {
+	defer close(done)
+
+	lastWrite := time.Now()
+	processedCount := 0
+	checkpointEntries := c.entryOffset
+
+	// Idle flushes are checked on a ticker rather than per span, so spans
+	// buffered before the input goes quiet (e.g. -follow) are still written
+	flushInterval := c.config.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = defaultFlushInterval
+	}
+	ticker := time.NewTicker(min(flushInterval, time.Second))
+	defer ticker.Stop()
+
+	// Stdout gets a single export, so nothing is flushed before the end
+	holdAll := c.config.WritesToStdout()
+
+	for {
+		var span *OTLPSpan
+		select {
+		case s, ok := <-resultChan:
+			if !ok {
+				// Final flush
+				c.flushTraces(c.consumedEntries())
+				return
+			}
+			span = s
+		case <-ticker.C:
+			if !c.config.KeepTracesTogether && !holdAll && time.Since(lastWrite) >= flushInterval && c.bufferedWindows() > 0 {
+				checkpointEntries = c.consumedEntries()
+				c.flushTraces(checkpointEntries)
+				lastWrite = time.Now()
+				slog.Info("queued spans for writing", "spans", processedCount)
+			}
+			continue
+		}
+
+		// Spans still in flight when input is stopped are not written, so
+		// their entries are not marked collected and the checkpoint stays
+		// before them
+		if c.inputStopped() {
+			releaseSpan(span)
+			continue
+		}
+
+		window := c.timeWindow(span)
+
+		c.tracesLock.Lock()
+		buf, ok := c.buffers[window]
+		if ok && c.config.KeepTracesTogether && !holdAll && buf.spans >= c.config.WriteInterval {
+			if _, open := buf.traces[span.TraceID]; !open {
+				// A new trace starts: the buffered ones are taken as complete
+				c.tracesLock.Unlock()
+				if c.bufferedWindows() == 1 {
+					checkpointEntries = c.consumedEntries()
+				}
+				c.flushWindow(window, checkpointEntries)
+				lastWrite = time.Now()
+				slog.Info("queued spans for writing", "spans", processedCount, "window", window)
+				c.tracesLock.Lock()
+				ok = false
+			}
+		}
+		if !ok {
+			buf = &traceBuffer{traces: make(map[string][]*OTLPSpan)}
+			c.buffers[window] = buf
+		}
+		// Best-effort guard against runaway traces: the limit applies per
+		// buffer, so a trace can exceed it across flushes
+		if limit := c.config.MaxSpansPerTrace; limit > 0 && len(buf.traces[span.TraceID]) >= limit {
+			c.tracesLock.Unlock()
+			c.traceLimitDrops.Add(1)
+			c.spanCollected(span)
+			releaseSpan(span)
+			continue
+		}
+		spans, buffered := buf.traces[span.TraceID]
+		if !buffered {
+			c.seenTraces[span.TraceIDBytes] = struct{}{}
+		}
+		c.serviceSpans[spanServiceName(span)]++
+		buf.traces[span.TraceID] = append(spans, span)
+		buf.spans++
+		full := !c.config.KeepTracesTogether && !holdAll && buf.spans >= c.config.WriteInterval
+		c.tracesLock.Unlock()
+		c.spanCollected(span)
+
+		processedCount++
+
+		// Check if we should write
+		if full {
+			// Input is only fully covered once no other window is still buffered
+			if c.bufferedWindows() == 1 {
+				checkpointEntries = c.consumedEntries()
+			}
+			c.flushWindow(window, checkpointEntries)
+			lastWrite = time.Now()
+			slog.Info("queued spans for writing", "spans", processedCount, "window", window)
+		} else if c.config.MaxMemoryMB > 0 && processedCount%memoryCheckInterval == 0 && c.overMemoryLimit() {
+			c.memoryFlushes.Add(1)
+			checkpointEntries = c.consumedEntries()
+			c.flushTraces(checkpointEntries)
+			lastWrite = time.Now()
+			slog.Warn("heap over -max-memory-mb, flushed early", "spans", processedCount, "max_memory_mb", c.config.MaxMemoryMB)
+		}
+	}
+}
+
+// consumedEntries returns how many leading input entries are fully covered
+// by the spans collected so far: every span they produced is buffered or was
+// dropped, whatever order the workers finished them in. Entries that failed
+// to parse or whose spans were all filtered count as covered once a worker
+// has handled them. Without a checkpoint nothing is tracked.
+func (c *Converter) consumedEntries() int64 {
+	if c.progress == nil {
+		return c.entryOffset
+	}
+	return c.entryOffset + c.progress.covered()
+}
+
+// spanCollected records that the collector has buffered or deliberately
+// dropped a span, for the checkpoint
+func (c *Converter) spanCollected(span *OTLPSpan) {
+	if c.progress != nil {
+		c.progress.collected(span.entrySeq)
+	}
+}
+
+// bufferedWindows returns the number of time windows holding spans
+func (c *Converter) bufferedWindows() int {
+	c.tracesLock.Lock()
+	defer c.tracesLock.Unlock()
+	return len(c.buffers)
+}
+
+// flushTraces sends every buffered window to the writer
+func (c *Converter) flushTraces(entries int64) {
+	c.tracesLock.Lock()
+	windows := make([]string, 0, len(c.buffers))
+	for window := range c.buffers {
+		windows = append(windows, window)
+	}
+	c.tracesLock.Unlock()
+
+	sort.Strings(windows)
+	for _, window := range windows {
+		c.flushWindow(window, entries)
+	}
+}
+
+// flushWindow sends one window's buffered traces to the writer
+func (c *Converter) flushWindow(window string, entries int64) {
+	c.tracesLock.Lock()
+	buf, ok := c.buffers[window]
+	delete(c.buffers, window)
+	c.tracesLock.Unlock()
+
+	if !ok || len(buf.traces) == 0 {
+		return
+	}
+	if c.inputStopped() {
+		releaseTraces(buf.traces)
+		return
+	}
+	if c.config.KeepTracesTogether {
+		c.recordTraceSizes(buf.traces)
+	}
+
+	// Send to writer (non-blocking)
+	batch := writeBatch{traces: buf.traces, window: window, entries: entries, seq: c.flushSeq}
+	c.flushSeq++
+	if c.config.LimitBatches > 0 && c.flushSeq == int64(c.config.LimitBatches) {
+		c.batchLimitHit.Store(true)
+		c.stopReading()
+		slog.Info("batch limit reached, stopping input", "batches", c.flushSeq)
+	}
+	select {
+	case c.writeChan <- batch:
+	default:
+		// If channel full, write synchronously
+		c.backpressureEvents.Add(1)
+		c.writeOutput(batch)
+	}
+}
+
+// recordTraceSizes tracks the largest trace flushed so far
+func (c *Converter) recordTraceSizes(traces map[string][]*OTLPSpan) {
+	largest := 0
+	for _, spans := range traces {
+		largest = max(largest, len(spans))
+	}
+	for {
+		current := c.maxTraceSpans.Load()
+		if int64(largest) <= current || c.maxTraceSpans.CompareAndSwap(current, int64(largest)) {
+			return
+		}
+	}
+}
+
+// BackgroundWriter writes flushed batches until Shutdown is called. Several
+// BackgroundWriters (Config.IOWorkers) may drain the same converter, writing
+// batches concurrently.
+func (c *Converter) BackgroundWriter(done chan<- struct{}) {
+	defer close(done)
+	c.activeWriters.Add(1)
+
+	for batch := range c.writeChan {
+		c.writeOutput(batch)
+	}
+
+	// The last writer to stop finishes the files kept open across batches
+	if c.activeWriters.Add(-1) == 0 {
+		c.finishArrowAppends()
+	}
+}
+
+func (c *Converter) writeToArrow(prefix string, traces map[string][]*OTLPSpan, batchNum int) {
+	filename := fmt.Sprintf("%s.batch_%04d.arrow", prefix, batchNum)
+	if c.config.ArrowAppend {
+		filename = prefix + ".arrow"
+	}
+
+	rows := c.spanRows(traces, c.config.ArrowSpanEncoding == "proto")
+	spanCount := len(rows)
+
+	// Write to Arrow file
+	opts := ArrowWriteOptions{
+		ChunkSize:     c.config.ArrowChunkSize,
+		SchemaVersion: c.config.ArrowSchemaVersion,
+		Metadata:      c.arrowMetadata(),
+		TimeType:      c.arrowTimeType(),
+		SpanEncoding:  c.config.ArrowSpanEncoding,
+	}
+	var err error
+	if c.config.ArrowAppend {
+		err = c.appendArrowBatch(filename, rows, opts)
+	} else {
+		err = c.writeWithRetry(filename, func(path string) error {
+			return WriteArrowFileOptions(path, rows, opts)
+		})
+	}
+	if err != nil {
+		c.lostBatches.Add(1)
+		slog.Error("failed to write Arrow file", "batch", batchNum, "filename", filename, "spans", spanCount, "error", err)
+		return
+	}
+
+	c.statsLock.Lock()
+	c.totalSpans += spanCount
+	c.statsLock.Unlock()
+
+	slog.Info("wrote batch", "format", "arrow", "spans", spanCount, "batch", batchNum, "filename", filename)
+}
+
+// spanRows serializes every span of traces, in traceOrder, into a row of the
+// indexed Arrow columns (also used for CSV output). With asProto the span is
+// encoded as an OTLP Span message in OTLPSpanProto instead of JSON.
+func (c *Converter) spanRows(traces map[string][]*OTLPSpan, asProto bool) []ArrowRow {
+	total := 0
+	for _, spans := range traces {
+		total += len(spans)
+	}
+	rows := make([]ArrowRow, 0, total)
+
+	// Serialization scratch space, reused for every span in the batch: one
+	// encoder buffer, and one span copy whose attributes get the resource
+	// folded in
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	var rowSpan OTLPSpan
+	var rowAttrs []Attribute
+
+	for _, traceID := range c.traceOrder(traces) {
+		for _, span := range traces[traceID] {
+			// Arrow rows are self-contained, so fold resource attributes back into the span
+			rowSpan = *span
+			if len(span.Resource) > 0 || span.Scope != (InstrumentationScope{}) {
+				var dropped int
+				rowAttrs, dropped = c.appendRowAttributes(rowAttrs[:0], span)
+				if dropped > 0 {
+					c.attrConflicts.Add(int64(dropped))
+				}
+				rowSpan.Attributes = rowAttrs
+			}
+
+			serviceName := spanServiceName(span)
+
+			row := ArrowRow{
+				TraceID:      span.TraceID,
+				SpanID:       span.SpanID,
+				TraceIDBytes: span.TraceIDBytes[:],
+				SpanIDBytes:  span.SpanIDBytes[:],
+				ServiceName:  serviceName,
+				Name:         span.Name,
+			}
+			if asProto {
+				row.OTLPSpanProto = marshalSpan(&rowSpan)
+			} else {
+				// Serialize full OTLP span to JSON. Encode matches
+				// json.Marshal apart from the trailing newline.
+				buf.Reset()
+				if err := encoder.Encode(&rowSpan); err != nil {
+					continue
+				}
+				row.OTLPSpan = string(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+			}
+			if c.arrowTimeType() != "" {
+				// Formatted from int64 in conversion, so always parseable
+				row.StartTime, _ = strconv.ParseInt(span.StartTimeUnixNano, 10, 64)
+				row.EndTime, _ = strconv.ParseInt(span.EndTimeUnixNano, 10, 64)
+			}
+
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// arrowTimeType returns Config.ArrowTimeType as ArrowWriteOptions.TimeType
+func (c *Converter) arrowTimeType() string {
+	if c.config.ArrowTimeType == "none" {
+		return ""
+	}
+	return c.config.ArrowTimeType
+}
+
+// arrowMetadata describes the run in Arrow schema metadata, so a file's
+// provenance can be read from the file alone. created_at is left out with
+// Deterministic so identical input still gives identical files.
+func (c *Converter) arrowMetadata() map[string]string {
+	sourceFormat := "jaeger"
+	if c.config.InputFormat == "zipkin" {
+		sourceFormat = "zipkin"
+	}
+	metadata := map[string]string{
+		"converter_version": Version,
+		"source_format":     sourceFormat,
+	}
+	if !c.config.Deterministic {
+		metadata["created_at"] = time.Now().UTC().Format(time.RFC3339)
+	}
+	return metadata
+}
+
+func (c *Converter) writeToCSV(prefix string, traces map[string][]*OTLPSpan, batchNum int) {
+	filename := fmt.Sprintf("%s.batch_%04d.csv", prefix, batchNum)
+	rows := c.spanRows(traces, false)
+
+	err := c.writeWithRetry(filename, func(path string) error {
+		return WriteCSVFile(path, rows)
+	})
+	if err != nil {
+		c.lostBatches.Add(1)
+		slog.Error("failed to write CSV file", "batch", batchNum, "filename", filename, "spans", len(rows), "error", err)
+		return
+	}
+
+	c.statsLock.Lock()
+	c.totalSpans += len(rows)
+	c.statsLock.Unlock()
+
+	slog.Info("wrote batch", "format", "csv", "spans", len(rows), "batch", batchNum, "filename", filename)
+}
+
+func (c *Converter) writeToClickHouseTSV(prefix string, traces map[string][]*OTLPSpan, batchNum int) {
+	filename := fmt.Sprintf("%s.batch_%04d.tsv", prefix, batchNum)
+	var spans []*OTLPSpan
+	for _, traceID := range c.traceOrder(traces) {
+		spans = append(spans, traces[traceID]...)
+	}
+
+	err := c.writeWithRetry(filename, func(path string) error {
+		return WriteClickHouseTSV(path, spans)
+	})
+	if err != nil {
+		c.lostBatches.Add(1)
+		slog.Error("failed to write ClickHouse TSV file", "batch", batchNum, "filename", filename, "spans", len(spans), "error", err)
+		return
+	}
+
+	c.statsLock.Lock()
+	c.totalSpans += len(spans)
+	c.statsLock.Unlock()
+
+	slog.Info("wrote batch", "format", "clickhouse-tsv", "spans", len(spans), "batch", batchNum, "filename", filename)
+}
+
+// writeOutput writes traces in the configured format(s)
+func (c *Converter) writeOutput(batch writeBatch) {
+	// Once the disk is full, queued batches are not attempted
+	if c.diskFull.Load() {
+		c.lostBatches.Add(1)
+		releaseTraces(batch.traces)
+		return
+	}
+
+	c.statsLock.Lock()
+	batchNum := c.batchCount
+	c.batchCount++
+	c.statsLock.Unlock()
+
+	if c.config.Deterministic {
+		sortTraceSpans(batch.traces)
+	}
+
+	for _, part := range c.partition(batch) {
+		switch c.config.OutputFormat {
+		case "json":
+			if c.config.OneFilePerTrace {
+				c.writeTraceFiles(part.prefix, part.traces, batchNum)
+				continue
+			}
+			c.writeToOTLPJSON(part.prefix, part.traces, batchNum)
+		case "protobuf":
+			c.writeToOTLPProto(part.prefix, part.traces, batchNum)
+		case "http":
+			c.exportHTTP(part.traces, batchNum)
+		case "csv":
+			c.writeToCSV(part.prefix, part.traces, batchNum)
+		case "clickhouse-tsv":
+			c.writeToClickHouseTSV(part.prefix, part.traces, batchNum)
+		case "both":
+			c.writeToArrow(part.prefix, part.traces, batchNum)
+			c.writeToOTLPJSON(part.prefix, part.traces, batchNum)
+		default: // "arrow"
+			c.writeToArrow(part.prefix, part.traces, batchNum)
+		}
+	}
+
+	c.saveCheckpoint(batch.seq, batch.entries, batchNum)
+
+	// Every output has been written, so the spans can be reused
+	releaseTraces(batch.traces)
+}
+
+// buildOTLPExport groups traces into OTLP ResourceSpans by resource, visiting
+// traces in the given order, and returns the export along with the number of
+// spans it holds. A non-empty schemaURL is set on every ResourceSpans and
+// ScopeSpans.
+func buildOTLPExport(traces map[string][]*OTLPSpan, order []string, schemaURL string) (OTLPExport, int) {
+	resourceSpansList := make([]ResourceSpans, 0)
+	spanCount, _ := eachResourceSpans(traces, order, schemaURL, func(rs *ResourceSpans) error {
+		resourceSpansList = append(resourceSpansList, *rs)
+		return nil
+	})
+	return OTLPExport{ResourceSpans: resourceSpansList}, spanCount
+}
+
+// eachResourceSpans groups traces by resource, visiting traces in the given
+// order, and calls fn with the ResourceSpans of each resource in the order
+// the resources first appear. Only one ResourceSpans is built at a time, and
+// fn must not keep it. It returns the number of spans grouped, and stops at
+// the first error from fn.
+func eachResourceSpans(traces map[string][]*OTLPSpan, order []string, schemaURL string, fn func(*ResourceSpans) error) (int, error) {
+	// Group spans by their full set of resource attributes
+	resourceGroups := make(map[string][]*OTLPSpan)
+	resourceOrder := make([]string, 0)
+	spanCount := 0
+
+	for _, traceID := range order {
+		for _, span := range traces[traceID] {
+			key := resourceKey(span.Resource)
+			if _, ok := resourceGroups[key]; !ok {
+				resourceOrder = append(resourceOrder, key)
+			}
+			resourceGroups[key] = append(resourceGroups[key], span)
+			spanCount++
+		}
+	}
+
+	// Build each OTLP ResourceSpans structure in turn
+	for _, key := range resourceOrder {
+		spans := resourceGroups[key]
+		delete(resourceGroups, key)
+		group := ResourceSpans{
+			Resource: Resource{
+				Attributes: spans[0].Resource,
+			},
+			SchemaURL: schemaURL,
+		}
+		for _, span := range spans {
+			scopeSpans := group.scopeSpans(span.Scope)
+			scopeSpans.Spans = append(scopeSpans.Spans, span)
+		}
+		for i := range group.ScopeSpans {
+			group.ScopeSpans[i].SchemaURL = schemaURL
+		}
+		if err := fn(&group); err != nil {
+			return spanCount, err
+		}
+	}
+	return spanCount, nil
+}
+
+// writeToOTLPJSON writes traces directly to OTLP JSON format, grouped by
+// resource into a single TracesData.
+// With GroupBy "trace" the file is JSON Lines instead (.otlp.jsonl): one
+// compact OTLP TracesData per line, each holding exactly one trace.
+func (c *Converter) writeToOTLPJSON(prefix string, traces map[string][]*OTLPSpan, batchNum int) {
+	filename := fmt.Sprintf("%s.batch_%04d.otlp.json", prefix, batchNum)
+	order := c.traceOrder(traces)
+	var encode func(w io.Writer) error
+	var spanCount, resourceSpans int
+	if c.config.GroupBy == "trace" {
+		filename += "l"
+		var exports []OTLPExport
+		exports, spanCount = buildTraceExports(traces, order, c.config.SchemaURL)
+		for _, otlpExport := range exports {
+			resourceSpans += len(otlpExport.ResourceSpans)
+		}
+		encode = func(w io.Writer) error {
+			return c.encodeOTLPJSON(w, exports)
+		}
+	} else {
+		// The batch's single export can be large, so it is streamed
+		// resource by resource rather than built first
+		for _, spans := range traces {
+			spanCount += len(spans)
+		}
+		encode = func(w io.Writer) error {
+			var err error
+			resourceSpans, err = streamOTLPJSON(w, traces, order, c.config.SchemaURL, c.config.Pretty)
+			return err
+		}
+	}
+
+	// Write JSON file, or stdout, which cannot be rewritten and so is not retried
+	var err error
+	if c.config.WritesToStdout() {
+		filename = "-"
+		err = c.writeOTLPJSONStdout(encode)
+	} else {
+		err = c.writeWithRetry(filename, func(path string) error {
+			return c.writeOTLPJSONFile(path, encode)
+		})
+	}
+	if err != nil {
+		c.lostBatches.Add(1)
+		slog.Error("failed to write OTLP JSON file", "batch", batchNum, "filename", filename, "spans", spanCount, "error", err)
+		return
+	}
+
+	c.statsLock.Lock()
+	c.totalSpans += spanCount
+	c.statsLock.Unlock()
+
+	slog.Info("wrote batch", "format", "json", "spans", spanCount, "batch", batchNum, "filename", filename, "resource_spans", resourceSpans)
+}
+
+// buildTraceExports builds one OTLP export per trace, in the given order,
+// and returns them along with the total number of spans
+func buildTraceExports(traces map[string][]*OTLPSpan, order []string, schemaURL string) ([]OTLPExport, int) {
+	exports := make([]OTLPExport, 0, len(traces))
+	spanCount := 0
+	for _, traceID := range order {
+		otlpExport, n := buildOTLPExport(traces, []string{traceID}, schemaURL)
+		exports = append(exports, otlpExport)
+		spanCount += n
+	}
+	return exports, spanCount
+}
+
+// writeOTLPJSONFile writes filename with encode, replacing any partial file
+// left by an earlier attempt
+func (c *Converter) writeOTLPJSONFile(filename string, encode func(w io.Writer) error) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(file)
+	if err := encode(w); err != nil {
+		file.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// writeOTLPJSONStdout writes to stdout with encode for OutputFile "-"
+func (c *Converter) writeOTLPJSONStdout(encode func(w io.Writer) error) error {
+	w := bufio.NewWriter(os.Stdout)
+	if err := encode(w); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// encodeOTLPJSON writes exports to w, one per line. Output is indented only
+// when Pretty is set and exports are not per trace. An export grouped by
+// resource is written resource by resource; per-trace exports are small and
+// encoded whole.
+func (c *Converter) encodeOTLPJSON(w io.Writer, exports []OTLPExport) error {
+	if c.config.GroupBy != "trace" {
+		for i := range exports {
+			if err := streamOTLPExport(w, &exports[i], c.config.Pretty); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	encoder := json.NewEncoder(w)
+	for _, otlpExport := range exports {
+		if err := encoder.Encode(otlpExport); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeToOTLPProto writes traces as a single OTLP TracesData protobuf message
+func (c *Converter) writeToOTLPProto(prefix string, traces map[string][]*OTLPSpan, batchNum int) {
+	filename := fmt.Sprintf("%s.batch_%04d.otlp.pb", prefix, batchNum)
+
+	otlpExport, spanCount := buildOTLPExport(traces, c.traceOrder(traces), c.config.SchemaURL)
+
+	data := MarshalOTLPProto(otlpExport)
+	err := c.writeWithRetry(filename, func(path string) error {
+		return os.WriteFile(path, data, 0o644)
+	})
+	if err != nil {
+		c.lostBatches.Add(1)
+		slog.Error("failed to write OTLP protobuf file", "batch", batchNum, "filename", filename, "spans", spanCount, "error", err)
+		return
+	}
+
+	c.statsLock.Lock()
+	c.totalSpans += spanCount
+	c.statsLock.Unlock()
+
+	slog.Info("wrote batch", "format", "protobuf", "spans", spanCount, "batch", batchNum, "filename", filename, "resource_spans", len(otlpExport.ResourceSpans))
+}
+
+// Shutdown stops the background writer once pending batches are written
+func (c *Converter) Shutdown() {
+	close(c.writeChan)
+}
+
+// Reset returns the converter to the state New left it in, so the same
+// instance can convert another input with the same Config: buffered traces,
+// counters, statistics, checkpoint progress and the input stop are cleared,
+// and the write channel closed by Shutdown is re-created. Maps are emptied
+// rather than reallocated.
+//
+// Reset is not safe for concurrent use. Call it only once the previous run
+// has fully stopped: workers and the collector have returned and, after
+// Shutdown, every BackgroundWriter has finished. Spans still buffered at that
+// point are released.
+func (c *Converter) Reset() {
+	for _, buf := range c.buffers {
+		releaseTraces(buf.traces)
+	}
+	clear(c.buffers)
+	clear(c.seenTraces)
+	clear(c.serviceSpans)
+	c.writeChan = make(chan writeBatch, cap(c.writeChan))
+	c.totalSpans = 0
+	c.batchCount = 0
+
+	for _, counter := range []*atomic.Int64{
+		&c.entriesProcessed, &c.parseErrors, &c.backpressureEvents,
+		&c.invalidTimestamps, &c.endBeforeStart, &c.unknownTagTypes,
+		&c.traceLimitDrops, &c.outsideTimeRange, &c.lostBatches,
+		&c.sampledOut, &c.traceIDFiltered, &c.traceFileDrops,
+		&c.malformedIDs, &c.attrConflicts, &c.maxTraceSpans,
+		&c.memoryFlushes, &c.otelNativeSpans, &c.unknownServiceDrops,
+		&c.unresolvedProcesses, &c.invalidSpans,
+	} {
+		counter.Store(0)
+	}
+	for i := range c.violations {
+		c.violations[i].Store(0)
+	}
+
+	if c.parseLatency != nil {
+		c.parseLatency = &latencyHistogram{}
+	}
+	if c.limiter != nil {
+		c.limiter = newRateLimiter(c.config.RateLimit)
+	}
+	if c.processes != nil {
+		c.processes = &processCache{resources: make(map[string][]Attribute)}
+	}
+	clear(c.traceFiles)
+
+	c.entryOffset = 0
+	if c.progress != nil {
+		c.progress = newEntryProgress()
+	}
+	c.flushSeq = 0
+	c.stopInput = make(chan struct{})
+	c.stopOnce = sync.Once{}
+	c.batchLimitHit.Store(false)
+	c.diskFull.Store(false)
+	c.checkpoint = Checkpoint{}
+	c.checkpointSeq = 0
+	clear(c.pendingFlushes)
+}
+
+// TotalSpans returns the number of spans written so far
+func (c *Converter) TotalSpans() int {
+	c.statsLock.Lock()
+	defer c.statsLock.Unlock()
+	return c.totalSpans
+}
+
+// BatchCount returns the number of batches written so far
+func (c *Converter) BatchCount() int {
+	c.statsLock.Lock()
+	defer c.statsLock.Unlock()
+	return c.batchCount
+}
+
+// EntriesProcessed returns the number of input entries handled by workers
+func (c *Converter) EntriesProcessed() int64 {
+	return c.entriesProcessed.Load()
+}
+
+// ParseErrors returns the number of entries whose value could not be decoded
+func (c *Converter) ParseErrors() int64 {
+	return c.parseErrors.Load()
+}
+
+// BackpressureEvents returns how many flushes found the writer queue full and
+// had to write synchronously, stalling the collector
+func (c *Converter) BackpressureEvents() int64 {
+	return c.backpressureEvents.Load()
+}
+
+// InvalidTimestamps returns how many spans had a zero or pre-epoch start time
+func (c *Converter) InvalidTimestamps() int64 {
+	return c.invalidTimestamps.Load()
+}
+
+// UnknownTagTypes returns how many tags had an unrecognized value type and
+// were converted as strings
+func (c *Converter) UnknownTagTypes() int64 {
+	return c.unknownTagTypes.Load()
+}
+
+// EndBeforeStart returns how many spans had an end time before their start,
+// clamped or flagged according to Config.EndBeforeStart
+func (c *Converter) EndBeforeStart() int64 {
+	return c.endBeforeStart.Load()
+}
+
+// MalformedIDs returns how many spans were dropped because a trace, span or
+// reference ID could not be marshaled to its full width
+func (c *Converter) MalformedIDs() int64 {
+	return c.malformedIDs.Load()
+}
+
+// AttrConflicts returns how many attributes were left out of Arrow and CSV
+// rows because the span and its resource both set the key
+func (c *Converter) AttrConflicts() int64 {
+	return c.attrConflicts.Load()
+}
+
+// MaxTraceSpans returns the span count of the largest trace flushed with
+// KeepTracesTogether, and 0 otherwise
+func (c *Converter) MaxTraceSpans() int64 {
+	return c.maxTraceSpans.Load()
+}
+
+// MemoryFlushes returns how many flushes were forced by MaxMemoryMB
+func (c *Converter) MemoryFlushes() int64 {
+	return c.memoryFlushes.Load()
+}
+
+// TraceLimitDrops returns how many spans were dropped by MaxSpansPerTrace
+func (c *Converter) TraceLimitDrops() int64 {
+	return c.traceLimitDrops.Load()
+}
+
+// LostBatches returns how many batch files could not be written even after
+// retrying. Their spans are missing from the output.
+func (c *Converter) LostBatches() int64 {
+	return c.lostBatches.Load()
+}
+
+// ExportRate returns the average spans per second sent to the endpoint with
+// Config.RateLimit, from the first request to the last successful one, and
+// how long writers were held back by the limit in total
+func (c *Converter) ExportRate() (float64, time.Duration) {
+	if c.limiter == nil {
+		return 0, 0
+	}
+	return c.limiter.effectiveRate()
+}
+
+// StopInput returns a channel that is closed once the converter wants no more
+// entries, so the caller can stop feeding them: Config.LimitBatches batches
+// have been flushed, or the output disk is full
+func (c *Converter) StopInput() <-chan struct{} {
+	return c.stopInput
+}
+
+// stopReading closes the StopInput channel; later calls do nothing
+func (c *Converter) stopReading() {
+	c.stopOnce.Do(func() { close(c.stopInput) })
+}
+
+// inputStopped reports whether the StopInput channel is closed
+func (c *Converter) inputStopped() bool {
+	select {
+	case <-c.stopInput:
+		return true
+	default:
+		return false
+	}
+}
+
+// BatchLimitHit reports whether Config.LimitBatches was reached
+func (c *Converter) BatchLimitHit() bool {
+	return c.batchLimitHit.Load()
+}
+
+// DiskFull reports whether a write failed because the output disk was full.
+// The run stops at that point; batches not yet written are counted as lost.
+func (c *Converter) DiskFull() bool {
+	return c.diskFull.Load()
+}
+
+// OutsideTimeRange returns how many spans were dropped by Since/Until
+func (c *Converter) OutsideTimeRange() int64 {
+	return c.outsideTimeRange.Load()
+}
+
+// UnknownServiceDrops returns how many spans were dropped by
+// Config.DropUnknownService for having no service name
+func (c *Converter) UnknownServiceDrops() int64 {
+	return c.unknownServiceDrops.Load()
+}
+
+// OTelNativeSpans returns how many spans were recognized as translated from
+// OTLP and had their otel.* tags mapped back with Config.OTelNativeTags
+func (c *Converter) OTelNativeSpans() int64 {
+	return c.otelNativeSpans.Load()
+}
+
+// DistinctTraces returns how many distinct traces were collected for output
+// this run, counted across flushes
+func (c *Converter) DistinctTraces() int {
+	c.tracesLock.Lock()
+	defer c.tracesLock.Unlock()
+	return len(c.seenTraces)
+}
+
+// DistinctServices returns how many distinct service names the spans
+// collected for output this run carried
+func (c *Converter) DistinctServices() int {
+	c.tracesLock.Lock()
+	defer c.tracesLock.Unlock()
+	return len(c.serviceSpans)
+}
+
+// ServiceSpans returns how many spans were collected for output this run
+// per service name. The map is a copy.
+func (c *Converter) ServiceSpans() map[string]int64 {
+	c.tracesLock.Lock()
+	defer c.tracesLock.Unlock()
+	counts := make(map[string]int64, len(c.serviceSpans))
+	for service, n := range c.serviceSpans {
+		counts[service] = n
+	}
+	return counts
+}
+
+// DistinctProcesses returns how many distinct processes were seen with
+// Config.DedupProcesses set, or 0 without it
+func (c *Converter) DistinctProcesses() int {
+	if c.processes == nil {
+		return 0
+	}
+	return c.processes.len()
+}
+
+// TraceIDFiltered returns how many spans were dropped because their trace
+// is not in Config.TraceIDs
+func (c *Converter) TraceIDFiltered() int64 {
+	return c.traceIDFiltered.Load()
+}
+
+// SampledOut returns how many spans were dropped by Config.Sample
+func (c *Converter) SampledOut() int64 {
+	return c.sampledOut.Load()
+}
+
+// ParseLatency returns the p-th percentile (0 < p <= 100) of time spent
+// decoding and converting one entry, rounded up to a power of two
+// nanoseconds. It is 0 unless Config.ProfileParse is set.
+func (c *Converter) ParseLatency(p float64) time.Duration {
+	if c.parseLatency == nil {
+		return 0
+	}
+	return c.parseLatency.percentile(p)
+}
+
+// BufferedTraces returns the number of traces waiting for the next flush
+func (c *Converter) BufferedTraces() int {
+	c.tracesLock.Lock()
+	defer c.tracesLock.Unlock()
+
+	count := 0
+	for _, buf := range c.buffers {
+		count += len(buf.traces)
+	}
+	return count
+}
@@ -0,0 +1,412 @@
+package otlpconvert
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	jaeger "github.com/jaegertracing/jaeger/model"
+)
+
+// attr returns the attribute with key, if any
+func attr(attrs []Attribute, key string) (AttributeValue, bool) {
+	for _, a := range attrs {
+		if a.Key == key {
+			return a.Value, true
+		}
+	}
+	return AttributeValue{}, false
+}
+
+// TestSpanNaming checks the name is always the operation name, whatever the
+// tags and the settings that affect attributes
+func TestSpanNaming(t *testing.T) {
+	tags := []jaeger.KeyValue{
+		jaeger.String("span.kind", "server"),
+		jaeger.String("otel.library.name", "lib"),
+		jaeger.String("peer.service", "peer"),
+		jaeger.String("rpc.method", "Get"),
+		jaeger.String("db.operation", "SELECT"),
+	}
+	tests := []struct {
+		name      string
+		config    Config
+		operation string
+		wantAttr  string // key under which rpc.method's value ends up
+	}{
+		{name: "defaults", operation: "GET /users", wantAttr: "rpc.method"},
+		{name: "empty operation stays empty", operation: "", wantAttr: "rpc.method"},
+		{
+			name:      "rename map renames attributes only",
+			config:    Config{RenameMap: map[string]string{"rpc.method": "name"}},
+			operation: "GET /users",
+			wantAttr:  "name",
+		},
+		{
+			name:      "normalized keys",
+			config:    Config{NormalizeKeys: "underscores"},
+			operation: "GET /users",
+			wantAttr:  "rpc_method",
+		},
+		{
+			name:      "otel native tags",
+			config:    Config{OTelNativeTags: true},
+			operation: "GET /users",
+			wantAttr:  "rpc.method",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			span := testSpan(1)
+			span.OperationName = tt.operation
+			span.Tags = tags
+			otlp := New(tt.config).ConvertJaegerSpan(span)
+			if otlp == nil {
+				t.Fatal("span rejected")
+			}
+			if otlp.Name != tt.operation {
+				t.Errorf("name = %q, want %q", otlp.Name, tt.operation)
+			}
+			if otlp.Kind != "SPAN_KIND_SERVER" {
+				t.Errorf("kind = %q, want SPAN_KIND_SERVER", otlp.Kind)
+			}
+			if v, ok := attr(otlp.Attributes, tt.wantAttr); !ok || v.StringValue != "Get" {
+				t.Errorf("attribute %q = %+v, %v, want Get", tt.wantAttr, v, ok)
+			}
+		})
+	}
+}
+
+func TestEventsSortedByTime(t *testing.T) {
+	tests := []struct {
+		name string
+		logs []int64 // log timestamps in Unix nanoseconds, named by index
+		want string  // event names in output order
+	}{
+		{name: "single digit before two digits", logs: []int64{10, 9}, want: "e1,e0"},
+		{
+			name: "mixed digit counts",
+			logs: []int64{100, 9, 1700000000000000000, 10, 99999},
+			want: "e1,e3,e0,e4,e2",
+		},
+		{name: "already sorted", logs: []int64{1, 20, 300}, want: "e0,e1,e2"},
+		{name: "equal timestamps keep their order", logs: []int64{50, 7, 50, 7}, want: "e1,e3,e0,e2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			span := testSpan(1)
+			for i, nanos := range tt.logs {
+				span.Logs = append(span.Logs, jaeger.Log{
+					Timestamp: time.Unix(0, nanos),
+					Fields:    []jaeger.KeyValue{jaeger.String("event", fmt.Sprintf("e%d", i))},
+				})
+			}
+			otlp := New(Config{}).ConvertJaegerSpan(span)
+			var names []string
+			for _, event := range otlp.Events {
+				names = append(names, event.Name)
+			}
+			if got := strings.Join(names, ","); got != tt.want {
+				t.Errorf("events = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTraceFlags(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags jaeger.Flags
+		want  string
+	}{
+		{name: "unsampled", flags: 0, want: "00"},
+		{name: "sampled", flags: jaeger.SampledFlag, want: "01"},
+		{name: "debug only", flags: jaeger.DebugFlag, want: "00"},
+		{name: "sampled and debug", flags: jaeger.SampledFlag | jaeger.DebugFlag, want: "01"},
+		{name: "firehose", flags: jaeger.SampledFlag | jaeger.FirehoseFlag, want: "01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			span := testSpan(1)
+			span.Flags = tt.flags
+			if got := New(Config{}).ConvertJaegerSpan(span).TraceFlags; got != tt.want {
+				t.Errorf("traceFlags = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInvalidStartTime(t *testing.T) {
+	tests := []struct {
+		name        string
+		start       time.Time
+		duration    time.Duration
+		skip        bool
+		wantStart   string // "" when the span is dropped
+		wantEnd     string
+		wantInvalid int64
+	}{
+		{name: "zero start clamped", start: time.Time{}, duration: 5 * time.Millisecond, wantStart: "0", wantEnd: "5000000", wantInvalid: 1},
+		{name: "pre-epoch start clamped", start: time.Unix(-10, 0), duration: time.Second, wantStart: "0", wantEnd: "1000000000", wantInvalid: 1},
+		{name: "zero start with negative duration", start: time.Time{}, duration: -time.Second, wantStart: "0", wantEnd: "0", wantInvalid: 1},
+		{name: "zero start skipped", start: time.Time{}, duration: time.Millisecond, skip: true, wantInvalid: 1},
+		{name: "epoch is valid", start: time.Unix(0, 0), duration: time.Millisecond, wantStart: "0", wantEnd: "1000000"},
+		{name: "valid start", start: time.Unix(1700000000, 0), duration: time.Millisecond, wantStart: "1700000000000000000", wantEnd: "1700000000001000000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(Config{SkipInvalidTimestamps: tt.skip})
+			span := testSpan(1)
+			span.StartTime = tt.start
+			span.Duration = tt.duration
+			otlp := c.ConvertJaegerSpan(span)
+			switch {
+			case tt.wantStart == "" && otlp != nil:
+				t.Errorf("span kept, want it dropped")
+			case tt.wantStart != "" && otlp == nil:
+				t.Errorf("span dropped")
+			case otlp != nil && (otlp.StartTimeUnixNano != tt.wantStart || otlp.EndTimeUnixNano != tt.wantEnd):
+				t.Errorf("times = %s..%s, want %s..%s", otlp.StartTimeUnixNano, otlp.EndTimeUnixNano, tt.wantStart, tt.wantEnd)
+			}
+			if got := c.InvalidTimestamps(); got != tt.wantInvalid {
+				t.Errorf("invalid timestamps = %d, want %d", got, tt.wantInvalid)
+			}
+		})
+	}
+}
+
+func TestConvertTagValueTypes(t *testing.T) {
+	tests := []struct {
+		name        string
+		tag         jaeger.KeyValue
+		want        string // JSON of the attribute value
+		wantUnknown int64
+	}{
+		{name: "string", tag: jaeger.String("k", "v"), want: `{"stringValue":"v"}`},
+		{name: "bool", tag: jaeger.Bool("k", true), want: `{"boolValue":true}`},
+		{name: "bool false", tag: jaeger.Bool("k", false), want: `{"boolValue":false}`},
+		{name: "int64", tag: jaeger.Int64("k", -42), want: `{"intValue":-42}`},
+		{name: "float64", tag: jaeger.Float64("k", 1.5), want: `{"doubleValue":1.5}`},
+		{name: "binary as base64", tag: jaeger.Binary("k", []byte{0x00, 0xff, 0x10, 'a'}), want: `{"bytesValue":"AP8QYQ=="}`},
+		{
+			name:        "unknown type as string",
+			tag:         jaeger.KeyValue{Key: "k", VType: jaeger.ValueType(99), VStr: "raw"},
+			want:        `{"stringValue":"raw"}`,
+			wantUnknown: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(Config{})
+			attr := c.convertTag(tt.tag)
+			got, err := json.Marshal(attr.Value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("value = %s, want %s", got, tt.want)
+			}
+			if got := c.UnknownTagTypes(); got != tt.wantUnknown {
+				t.Errorf("unknown tag types = %d, want %d", got, tt.wantUnknown)
+			}
+		})
+	}
+}
+
+func TestRepeatedTagKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		tags []jaeger.KeyValue
+		want string // JSON of the span attributes
+	}{
+		{
+			name: "three tags share a key",
+			tags: []jaeger.KeyValue{jaeger.String("k", "a"), jaeger.String("k", "b"), jaeger.String("k", "c")},
+			want: `[{"key":"k","value":{"arrayValue":{"values":[{"stringValue":"a"},{"stringValue":"b"},{"stringValue":"c"}]}}}]`,
+		},
+		{
+			name: "repeats interleaved with other keys",
+			tags: []jaeger.KeyValue{jaeger.String("k", "a"), jaeger.Int64("n", 1), jaeger.Int64("k", 2), jaeger.Bool("k", true)},
+			want: `[{"key":"k","value":{"arrayValue":{"values":[{"stringValue":"a"},{"intValue":2},{"boolValue":true}]}}},{"key":"n","value":{"intValue":1}}]`,
+		},
+		{
+			name: "distinct keys stay scalar",
+			tags: []jaeger.KeyValue{jaeger.String("a", "1"), jaeger.String("b", "2")},
+			want: `[{"key":"a","value":{"stringValue":"1"}},{"key":"b","value":{"stringValue":"2"}}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			span := testSpan(1)
+			span.Tags = tt.tags
+			got, err := json.Marshal(New(Config{}).ConvertJaegerSpan(span).Attributes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("attributes =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEndBeforeStart(t *testing.T) {
+	tests := []struct {
+		name        string
+		duration    time.Duration
+		mode        string
+		wantEnd     string // "" when the end is kept as computed
+		wantWarning bool
+		wantCount   int64
+	}{
+		{name: "valid duration", duration: time.Second, wantEnd: "1700000001000000000"},
+		{name: "negative duration clamped", duration: -time.Second, wantEnd: "1700000000000000000", wantCount: 1},
+		{name: "negative duration flagged", duration: -time.Second, mode: "flag", wantEnd: "1699999999000000000", wantWarning: true, wantCount: 1},
+		{name: "near MaxInt64 clamped", duration: math.MaxInt64 - 1, mode: "clamp", wantEnd: "1700000000000000000", wantCount: 1},
+		{name: "MaxInt64 clamped", duration: math.MaxInt64, wantEnd: "1700000000000000000", wantCount: 1},
+		{name: "MaxInt64 flagged", duration: math.MaxInt64, mode: "flag", wantWarning: true, wantCount: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(Config{EndBeforeStart: tt.mode})
+			span := testSpan(1)
+			span.Duration = tt.duration
+			otlp := c.ConvertJaegerSpan(span)
+			if otlp.StartTimeUnixNano != "1700000000000000000" {
+				t.Errorf("start = %s, want 1700000000000000000", otlp.StartTimeUnixNano)
+			}
+			if tt.wantEnd != "" && otlp.EndTimeUnixNano != tt.wantEnd {
+				t.Errorf("end = %s, want %s", otlp.EndTimeUnixNano, tt.wantEnd)
+			}
+			warning, ok := attr(otlp.Attributes, "conversion.warning")
+			if ok != tt.wantWarning || (ok && warning.StringValue != "end time before start time") {
+				t.Errorf("conversion.warning = %+v, %v, want %v", warning, ok, tt.wantWarning)
+			}
+			if got := c.EndBeforeStart(); got != tt.wantCount {
+				t.Errorf("end before start = %d, want %d", got, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestTraceState(t *testing.T) {
+	tests := []struct {
+		name string
+		tags []jaeger.KeyValue
+		want string // traceState in the JSON output, "" when left out
+	}{
+		{name: "from tag", tags: []jaeger.KeyValue{jaeger.String("w3c.tracestate", "vendor=abc,other=1")}, want: "vendor=abc,other=1"},
+		{name: "no tag", tags: []jaeger.KeyValue{jaeger.String("k", "v")}},
+		{name: "empty tag", tags: []jaeger.KeyValue{jaeger.String("w3c.tracestate", "")}},
+		{name: "non-string tag", tags: []jaeger.KeyValue{jaeger.Int64("w3c.tracestate", 1)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			span := testSpan(1)
+			span.Tags = tt.tags
+			data, err := ConvertToOTLPJSON([]*jaeger.Span{span})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var export struct {
+				ResourceSpans []struct {
+					ScopeSpans []struct {
+						Spans []map[string]any `json:"spans"`
+					} `json:"scopeSpans"`
+				} `json:"resourceSpans"`
+			}
+			if err := json.Unmarshal(data, &export); err != nil {
+				t.Fatal(err)
+			}
+			got, ok := export.ResourceSpans[0].ScopeSpans[0].Spans[0]["traceState"]
+			if tt.want == "" {
+				if ok {
+					t.Errorf("traceState = %v, want it left out", got)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("traceState = %v, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestConvertTagRetention checks a converted scalar attribute does not keep
+// the tag it came from, and so its VBinary buffer, reachable
+func TestConvertTagRetention(t *testing.T) {
+	tests := []struct {
+		name string
+		tag  jaeger.KeyValue
+		want string // JSON of the attribute value
+	}{
+		{name: "bool", tag: jaeger.Bool("k", true), want: `{"boolValue":true}`},
+		{name: "int64", tag: jaeger.Int64("k", 7), want: `{"intValue":7}`},
+		{name: "float64", tag: jaeger.Float64("k", 2.5), want: `{"doubleValue":2.5}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(Config{})
+			freed := make(chan struct{})
+			attr := func() Attribute {
+				buf := new([1 << 20]byte)
+				runtime.SetFinalizer(buf, func(*[1 << 20]byte) { close(freed) })
+				tag := tt.tag
+				tag.VBinary = buf[:]
+				return c.convertTag(tag)
+			}()
+
+			released := false
+			for i := 0; i < 10 && !released; i++ {
+				runtime.GC()
+				select {
+				case <-freed:
+					released = true
+				case <-time.After(10 * time.Millisecond):
+				}
+			}
+			if !released {
+				t.Error("tag buffer still reachable from the converted attribute")
+			}
+			got, _ := json.Marshal(attr.Value)
+			if string(got) != tt.want {
+				t.Errorf("value = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDebugFlagAttribute(t *testing.T) {
+	tests := []struct {
+		name      string
+		flags     jaeger.Flags
+		wantDebug bool
+		wantFlags string
+	}{
+		{name: "no flags", flags: 0, wantFlags: "00"},
+		{name: "sampled", flags: jaeger.SampledFlag, wantFlags: "01"},
+		{name: "debug", flags: jaeger.DebugFlag, wantDebug: true, wantFlags: "00"},
+		{name: "sampled and debug", flags: jaeger.SampledFlag | jaeger.DebugFlag, wantDebug: true, wantFlags: "01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			span := testSpan(1)
+			span.Flags = tt.flags
+			otlp := New(Config{}).ConvertJaegerSpan(span)
+			value, ok := attr(otlp.Attributes, "jaeger.debug")
+			if ok != tt.wantDebug {
+				t.Errorf("jaeger.debug present = %v, want %v", ok, tt.wantDebug)
+			}
+			if ok && (value.BoolValue == nil || !*value.BoolValue) {
+				t.Errorf("jaeger.debug = %+v, want true", value)
+			}
+			if otlp.TraceFlags != tt.wantFlags {
+				t.Errorf("traceFlags = %q, want %q", otlp.TraceFlags, tt.wantFlags)
+			}
+		})
+	}
+}
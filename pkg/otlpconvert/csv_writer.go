@@ -0,0 +1,51 @@
+package otlpconvert
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"os"
+)
+
+// CSVSpanMaxLen is how many bytes of the otlp_span JSON a CSV row keeps.
+// Spreadsheets cap cells at around 32k characters, and the column is meant
+// for a quick look rather than a faithful copy.
+const CSVSpanMaxLen = 1024
+
+// csvHeader names the CSV columns, matching the Arrow schema
+var csvHeader = []string{"trace_id", "span_id", "service_name", "name", "otlp_span"}
+
+// WriteCSVFile writes OTLP spans as CSV with the same columns as the Arrow
+// output, for opening in a spreadsheet. The otlp_span column is cut to
+// CSVSpanMaxLen bytes, with "..." marking a cut value. Values containing
+// commas, quotes or newlines are quoted per RFC 4180.
+func WriteCSVFile(filename string, rows []ArrowRow) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer file.Close()
+
+	buf := bufio.NewWriter(file)
+	w := csv.NewWriter(buf)
+	if err := w.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		span := row.OTLPSpan
+		if len(span) > CSVSpanMaxLen {
+			span = truncateUTF8(span, CSVSpanMaxLen) + "..."
+		}
+		if err := w.Write([]string{row.TraceID, row.SpanID, row.ServiceName, row.Name, span}); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	if err := buf.Flush(); err != nil {
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,64 @@
+package otlpconvert
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+)
+
+// DoneMarker is the content of the completion marker written with
+// Config.DoneMarker, a summary of the finished run
+type DoneMarker struct {
+	Entries     int64     `json:"entries"`     // input entries read
+	Spans       int       `json:"spans"`       // spans written
+	Batches     int       `json:"batches"`     // batch files written, including those of resumed runs
+	ParseErrors int64     `json:"parseErrors"` // entries that failed to parse, within -max-errors
+	FinishedAt  time.Time `json:"finishedAt"`
+}
+
+// DoneMarkerFile returns the path of the completion marker, <output>.done
+func (c *Config) DoneMarkerFile() string {
+	return c.OutputFile + ".done"
+}
+
+// RemoveDoneMarker deletes the completion marker of an earlier run, so a
+// watcher does not take the output of this one as finished while it is
+// still being written. A missing marker is not an error.
+func (c *Config) RemoveDoneMarker() error {
+	if IsS3URL(c.OutputFile) {
+		bucket, key, err := ParseS3URL(c.DoneMarkerFile())
+		if err != nil {
+			return err
+		}
+		client, err := NewS3ClientFromEnv()
+		if err != nil {
+			return err
+		}
+		return client.DeleteObject(bucket, key)
+	}
+	if err := os.Remove(c.DoneMarkerFile()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// WriteDoneMarker writes the completion marker once every batch is written
+// and the writers have stopped; entries is the number of input entries read.
+// The marker is renamed into place, so its appearance alone signals that the
+// output is complete.
+func (c *Converter) WriteDoneMarker(entries int64) error {
+	data, err := json.Marshal(DoneMarker{
+		Entries:     entries,
+		Spans:       c.TotalSpans(),
+		Batches:     c.BatchCount(),
+		ParseErrors: c.ParseErrors(),
+		FinishedAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	return c.writeWithRetry(c.config.DoneMarkerFile(), func(path string) error {
+		return os.WriteFile(path, append(data, '\n'), 0o644)
+	})
+}
@@ -0,0 +1,191 @@
+package otlpconvert
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/gogo/protobuf/proto"
+	jaeger "github.com/jaegertracing/jaeger/model"
+)
+
+// ErrNotBadgerExport is wrapped by the errors of SeekEntries for input that
+// is valid JSON but not shaped like a BadgerDB export
+var ErrNotBadgerExport = errors.New("input does not look like a BadgerDB export")
+
+// SeekEntries reads a BadgerDB export ({"entries":[...]}) up to the first
+// element of its top-level entries array, so the entries can then be decoded
+// one at a time while decoder.More() holds. Other top-level keys, before or
+// after entries, are skipped whatever their values.
+func SeekEntries(decoder *json.Decoder) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return fmt.Errorf("failed to read JSON: %w", err)
+	}
+	if token != json.Delim('{') {
+		return fmt.Errorf("%w: top level is not a JSON object", ErrNotBadgerExport)
+	}
+
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return fmt.Errorf("failed to read JSON: %w", err)
+		}
+		if key != "entries" {
+			var skipped json.RawMessage
+			if err := decoder.Decode(&skipped); err != nil {
+				return fmt.Errorf("failed to read JSON: %w", err)
+			}
+			continue
+		}
+
+		token, err := decoder.Token()
+		if err != nil {
+			return fmt.Errorf("failed to read entries array: %w", err)
+		}
+		if token != json.Delim('[') {
+			return fmt.Errorf("%w: 'entries' is not an array", ErrNotBadgerExport)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: no 'entries' array", ErrNotBadgerExport)
+}
+
+// ErrOTLPSpanLine is returned by DecodeNDJSONEntry for a line holding an
+// OTLP JSON span rather than a BadgerEntry
+var ErrOTLPSpanLine = errors.New("looks like an OTLP span; -input-format ndjson expects Badger entries")
+
+// DecodeNDJSONEntry decodes one line of -input-format ndjson input. A line
+// holding an OTLP JSON span ({"traceId":...,"spanId":...}) would decode into
+// an empty entry and only fail later as a parse error, so it is reported as
+// ErrOTLPSpanLine instead.
+func DecodeNDJSONEntry(line []byte) (BadgerEntry, error) {
+	var entry BadgerEntry
+	if err := json.Unmarshal(line, &entry); err != nil {
+		return entry, err
+	}
+	if entry.Key == "" && len(entry.Value) == 0 {
+		var span struct {
+			TraceID json.RawMessage `json:"traceId"`
+			SpanID  json.RawMessage `json:"spanId"`
+		}
+		if json.Unmarshal(line, &span) == nil && span.TraceID != nil && span.SpanID != nil {
+			return entry, ErrOTLPSpanLine
+		}
+	}
+	return entry, nil
+}
+
+// EntryValue holds the value of a BadgerEntry. It decodes from either a JSON
+// string or a JSON array of byte values. Strings are kept as their bytes
+// (characters up to U+00FF map to single bytes, so raw binary exported as a
+// Latin-1 string survives) and decoded later according to the value encoding.
+type EntryValue []byte
+
+// UnmarshalJSON implements json.Unmarshaler
+func (v *EntryValue) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '[' {
+		var ints []int
+		if err := json.Unmarshal(data, &ints); err != nil {
+			return fmt.Errorf("invalid byte array value: %w", err)
+		}
+		values := make([]byte, len(ints))
+		for i, n := range ints {
+			if n < 0 || n > 255 {
+				return fmt.Errorf("invalid byte array value: %d out of range", n)
+			}
+			values[i] = byte(n)
+		}
+		*v = values
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*v = latin1Bytes(s)
+	return nil
+}
+
+// latin1Bytes converts a string to bytes, mapping characters up to U+00FF to a
+// single byte. Strings with wider characters are returned as UTF-8.
+func latin1Bytes(s string) []byte {
+	ascii := true
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return []byte(s)
+	}
+
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r > 0xFF {
+			return []byte(s)
+		}
+		out = append(out, byte(r))
+	}
+	return out
+}
+
+// decodeValue turns an entry value into protobuf bytes according to the
+// configured value encoding. Zipkin input values are JSON and kept as is.
+func (c *Converter) decodeValue(value EntryValue) ([]byte, error) {
+	if c.config.InputFormat == "zipkin" {
+		return value, nil
+	}
+	switch c.config.ValueEncoding {
+	case "base64":
+		out := make([]byte, base64.StdEncoding.DecodedLen(len(value)))
+		n, err := base64.StdEncoding.Decode(out, value)
+		return out[:n], err
+	case "raw":
+		return value, nil
+	default: // "hex"
+		out := make([]byte, hex.DecodedLen(len(value)))
+		n, err := hex.Decode(out, value)
+		return out[:n], err
+	}
+}
+
+// unmarshalSpans parses decoded value bytes into Jaeger spans according to
+// the configured value shape: a single span, or a batch (jaeger.Batch) whose
+// spans fall back to the batch process when they carry none. Spans with only
+// a ProcessID are first resolved through Config.ProcessMap. Zipkin input
+// holds one Zipkin JSON span per value, mapped onto the Jaeger model.
+func (c *Converter) unmarshalSpans(valueBytes []byte) ([]*jaeger.Span, error) {
+	if c.config.InputFormat == "zipkin" {
+		span, err := unmarshalZipkinSpan(valueBytes)
+		if err != nil {
+			return nil, err
+		}
+		return []*jaeger.Span{span}, nil
+	}
+	if c.config.ValueShape != "spanlist" {
+		var span jaeger.Span
+		if err := proto.Unmarshal(valueBytes, &span); err != nil {
+			return nil, err
+		}
+		c.resolveProcess(&span)
+		return []*jaeger.Span{&span}, nil
+	}
+
+	var batch jaeger.Batch
+	if err := proto.Unmarshal(valueBytes, &batch); err != nil {
+		return nil, err
+	}
+	for _, span := range batch.Spans {
+		c.resolveProcess(span)
+		if span.Process == nil {
+			span.Process = batch.Process
+		}
+	}
+	return batch.Spans, nil
+}
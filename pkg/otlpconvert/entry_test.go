@@ -0,0 +1,259 @@
+package otlpconvert
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	jaeger "github.com/jaegertracing/jaeger/model"
+)
+
+func TestSeekEntriesRejects(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		want      string // substring of the error
+		notExport bool   // error wraps ErrNotBadgerExport
+	}{
+		{name: "no entries key", input: `{"spans":[{"key":"a"}]}`, want: "no 'entries' array", notExport: true},
+		{name: "entries only nested", input: `{"meta":{"entries":[]}}`, want: "no 'entries' array", notExport: true},
+		{name: "empty object", input: `{}`, want: "no 'entries' array", notExport: true},
+		{name: "top-level array", input: `[{"key":"a"}]`, want: "top level is not a JSON object", notExport: true},
+		{name: "entries not an array", input: `{"entries":{"key":"a"}}`, want: "'entries' is not an array", notExport: true},
+		{name: "invalid JSON", input: `{"meta":`, want: "failed to read JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := SeekEntries(json.NewDecoder(strings.NewReader(tt.input)))
+			if err == nil {
+				t.Fatal("SeekEntries succeeded, want an error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %q, want it to contain %q", err, tt.want)
+			}
+			if tt.notExport {
+				if !errors.Is(err, ErrNotBadgerExport) {
+					t.Errorf("error = %q, want ErrNotBadgerExport", err)
+				}
+				if !strings.HasPrefix(err.Error(), "input does not look like a BadgerDB export: ") {
+					t.Errorf("error = %q, want the BadgerDB export message", err)
+				}
+			}
+		})
+	}
+}
+
+// testSpan returns a span of a 128-bit trace with the given span ID, 0 for an
+// invalid one
+func testSpan(id uint64) *jaeger.Span {
+	return &jaeger.Span{
+		TraceID:       jaeger.NewTraceID(1, 1),
+		SpanID:        jaeger.NewSpanID(id),
+		OperationName: "op",
+		StartTime:     time.Unix(1700000000, 0),
+		Duration:      time.Millisecond,
+		Tags:          []jaeger.KeyValue{jaeger.String("k", "v")},
+		Process:       &jaeger.Process{ServiceName: "svc"},
+	}
+}
+
+// marshalEntry encodes msg as a raw entry value
+func marshalEntry(t *testing.T, msg proto.Message) BadgerEntry {
+	t.Helper()
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return BadgerEntry{Key: "k", Value: data}
+}
+
+// spanIDs lists the span IDs of spans in order
+func spanIDs(spans []*OTLPSpan) []string {
+	var ids []string
+	for _, span := range spans {
+		ids = append(ids, span.SpanID)
+	}
+	return ids
+}
+
+func TestParseEntry(t *testing.T) {
+	tests := []struct {
+		name        string
+		shape       string
+		entry       func(t *testing.T) BadgerEntry
+		want        []string
+		parseErrors int64
+	}{
+		{
+			name:  "single span",
+			shape: "span",
+			entry: func(t *testing.T) BadgerEntry { return marshalEntry(t, testSpan(1)) },
+			want:  []string{"0000000000000001"},
+		},
+		{
+			name:  "single span with zero ID",
+			shape: "span",
+			entry: func(t *testing.T) BadgerEntry { return marshalEntry(t, testSpan(0)) },
+		},
+		{
+			name:  "batch keeps order",
+			shape: "spanlist",
+			entry: func(t *testing.T) BadgerEntry {
+				return marshalEntry(t, &jaeger.Batch{Spans: []*jaeger.Span{testSpan(3), testSpan(1), testSpan(2)}})
+			},
+			want: []string{"0000000000000003", "0000000000000001", "0000000000000002"},
+		},
+		{
+			name:  "batch leaves out rejected spans",
+			shape: "spanlist",
+			entry: func(t *testing.T) BadgerEntry {
+				return marshalEntry(t, &jaeger.Batch{Spans: []*jaeger.Span{testSpan(1), testSpan(0), testSpan(2)}})
+			},
+			want: []string{"0000000000000001", "0000000000000002"},
+		},
+		{
+			name:        "unparseable value",
+			shape:       "span",
+			entry:       func(t *testing.T) BadgerEntry { return BadgerEntry{Key: "k", Value: EntryValue{0xff, 0xff}} },
+			parseErrors: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(Config{ValueEncoding: "raw", ValueShape: tt.shape})
+			spans := c.parseEntry(tt.entry(t))
+			if got := spanIDs(spans); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("span IDs = %v, want %v", got, tt.want)
+			}
+			if got := c.ParseErrors(); got != tt.parseErrors {
+				t.Errorf("parse errors = %d, want %d", got, tt.parseErrors)
+			}
+		})
+	}
+}
+
+// TestParseEntrySingleSpan checks a single-span entry converts exactly as
+// the span does on its own
+func TestParseEntrySingleSpan(t *testing.T) {
+	c := New(Config{ValueEncoding: "raw", ValueShape: "span"})
+	spans := c.parseEntry(marshalEntry(t, testSpan(1)))
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	want := c.ConvertJaegerSpan(testSpan(1))
+	got, _ := json.Marshal(spans[0])
+	wantJSON, _ := json.Marshal(want)
+	if string(got) != string(wantJSON) {
+		t.Errorf("parseEntry span =\n%s\nwant\n%s", got, wantJSON)
+	}
+	if !reflect.DeepEqual(spans[0].Resource, want.Resource) {
+		t.Errorf("resource = %v, want %v", spans[0].Resource, want.Resource)
+	}
+}
+
+// TestWorkerSendsEverySpan checks the worker forwards every span of an
+// entry, not just the first
+func TestWorkerSendsEverySpan(t *testing.T) {
+	c := New(Config{ValueEncoding: "raw", ValueShape: "spanlist"})
+	entryChan := make(chan BadgerEntry, 2)
+	entryChan <- marshalEntry(t, &jaeger.Batch{Spans: []*jaeger.Span{testSpan(1), testSpan(2), testSpan(3)}})
+	entryChan <- marshalEntry(t, &jaeger.Batch{Spans: []*jaeger.Span{testSpan(4)}})
+	close(entryChan)
+
+	resultChan := make(chan *OTLPSpan, 8)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	c.Worker(entryChan, resultChan, &wg)
+	close(resultChan)
+
+	var spans []*OTLPSpan
+	for span := range resultChan {
+		spans = append(spans, span)
+	}
+	want := []string{"0000000000000001", "0000000000000002", "0000000000000003", "0000000000000004"}
+	if got := spanIDs(spans); !reflect.DeepEqual(got, want) {
+		t.Errorf("span IDs = %v, want %v", got, want)
+	}
+}
+
+func TestSeekEntries(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		keys  []string // keys of the entries decoded after seeking
+	}{
+		{
+			name:  "entries only",
+			input: `{"entries":[{"key":"a","value":""},{"key":"b","value":""}]}`,
+			keys:  []string{"a", "b"},
+		},
+		{
+			name:  "meta before entries",
+			input: `{"meta":{"version":2,"tables":["spans"],"nested":{"entries":[1]}},"entries":[{"key":"a","value":""}]}`,
+			keys:  []string{"a"},
+		},
+		{
+			name:  "scalars and arrays before entries",
+			input: `{"count":1,"exported":"2024-01-01","ok":true,"none":null,"list":[{"key":"x"}],"entries":[{"key":"a","value":""}],"trailer":{}}`,
+			keys:  []string{"a"},
+		},
+		{
+			name:  "empty entries",
+			input: `{"meta":{},"entries":[]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := json.NewDecoder(strings.NewReader(tt.input))
+			if err := SeekEntries(decoder); err != nil {
+				t.Fatalf("SeekEntries: %v", err)
+			}
+			var keys []string
+			for decoder.More() {
+				var entry BadgerEntry
+				if err := decoder.Decode(&entry); err != nil {
+					t.Fatal(err)
+				}
+				keys = append(keys, entry.Key)
+			}
+			if strings.Join(keys, ",") != strings.Join(tt.keys, ",") {
+				t.Errorf("entries = %v, want %v", keys, tt.keys)
+			}
+		})
+	}
+}
+
+func TestDecodeNDJSONEntry(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      string
+		wantKey   string
+		wantValue string
+		wantErr   error
+	}{
+		{name: "entry", line: `{"key":"k","value":"0a0b"}`, wantKey: "k", wantValue: "0a0b"},
+		{name: "OTLP span", line: `{"traceId":"0102030405060708090a0b0c0d0e0f10","spanId":"0102030405060708","name":"op"}`, wantErr: ErrOTLPSpanLine},
+		{name: "entry with OTLP-like fields", line: `{"key":"k","value":"0a","traceId":"01","spanId":"01"}`, wantKey: "k", wantValue: "0a"},
+		{name: "empty object", line: `{}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := DecodeNDJSONEntry([]byte(tt.line))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if entry.Key != tt.wantKey || string(entry.Value) != tt.wantValue {
+				t.Errorf("entry = %q/%q, want %q/%q", entry.Key, entry.Value, tt.wantKey, tt.wantValue)
+			}
+		})
+	}
+
+	if _, err := DecodeNDJSONEntry([]byte(`not json`)); err == nil || errors.Is(err, ErrOTLPSpanLine) {
+		t.Errorf("invalid JSON: error = %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package otlpconvert
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	jaeger "github.com/jaegertracing/jaeger/model"
+)
+
+// maxFlattenDepth limits how many object levels FlattenJSONTags expands;
+// objects nested deeper are kept as a JSON string
+const maxFlattenDepth = 4
+
+// appendTagAttributes appends the attributes for a tag: attr as converted by
+// convertTag, or, with Config.FlattenJSONTags, the expanded fields when the
+// tag is a string holding a JSON object. JSONTagStyle "kvlist" keeps them as
+// one kvlistValue attribute; otherwise there is one dotted attribute per
+// field. Redacted and hashed tags are never expanded.
+func (c *Converter) appendTagAttributes(attrs []Attribute, tag jaeger.KeyValue, attr Attribute) []Attribute {
+	if !c.config.FlattenJSONTags || tag.VType != jaeger.ValueType_STRING {
+		return append(attrs, attr)
+	}
+	if c.redactKeys[tag.Key] || c.redactKeys[attr.Key] || c.hashKeys[tag.Key] || c.hashKeys[attr.Key] {
+		return append(attrs, attr)
+	}
+	if !strings.HasPrefix(strings.TrimSpace(tag.VStr), "{") {
+		return append(attrs, attr)
+	}
+
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(tag.VStr), &object); err != nil || len(object) == 0 {
+		return append(attrs, attr)
+	}
+	if c.config.JSONTagStyle == "kvlist" {
+		return append(attrs, Attribute{Key: attr.Key, Value: jsonKvlist(object, 1)})
+	}
+	return flattenJSONObject(attrs, attr.Key, object, 1)
+}
+
+// flattenJSONObject appends one attribute per field of object, keyed
+// prefix.field, in sorted field order. Nested objects are expanded up to
+// maxFlattenDepth.
+func flattenJSONObject(attrs []Attribute, prefix string, object map[string]json.RawMessage, depth int) []Attribute {
+	for _, field := range sortedFields(object) {
+		key := prefix + "." + field
+		raw := bytes.TrimSpace(object[field])
+		if nested, ok := nestedObject(raw, depth); ok {
+			attrs = flattenJSONObject(attrs, key, nested, depth+1)
+			continue
+		}
+		if value, ok := jsonValue(raw); ok {
+			attrs = append(attrs, Attribute{Key: key, Value: value})
+		}
+	}
+	return attrs
+}
+
+// jsonKvlist converts object into a kvlistValue, in sorted field order.
+// Nested objects become nested kvlists up to maxFlattenDepth.
+func jsonKvlist(object map[string]json.RawMessage, depth int) AttributeValue {
+	kvlist := &KeyValueList{Values: make([]Attribute, 0, len(object))}
+	for _, field := range sortedFields(object) {
+		raw := bytes.TrimSpace(object[field])
+		if nested, ok := nestedObject(raw, depth); ok {
+			kvlist.Values = append(kvlist.Values, Attribute{Key: field, Value: jsonKvlist(nested, depth+1)})
+			continue
+		}
+		if value, ok := jsonValue(raw); ok {
+			kvlist.Values = append(kvlist.Values, Attribute{Key: field, Value: value})
+		}
+	}
+	return AttributeValue{KvlistValue: kvlist}
+}
+
+// sortedFields returns the field names of object in sorted order
+func sortedFields(object map[string]json.RawMessage) []string {
+	fields := make([]string, 0, len(object))
+	for field := range object {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// nestedObject parses raw as an object to expand, if it is one and depth
+// still allows expansion
+func nestedObject(raw json.RawMessage, depth int) (map[string]json.RawMessage, bool) {
+	if len(raw) == 0 || raw[0] != '{' || depth >= maxFlattenDepth {
+		return nil, false
+	}
+	var nested map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &nested); err != nil {
+		return nil, false
+	}
+	return nested, true
+}
+
+// jsonValue converts a JSON scalar to an attribute value. Arrays, and objects
+// not expanded by the caller, are kept as a compact JSON string; null
+// reports false.
+func jsonValue(raw json.RawMessage) (AttributeValue, bool) {
+	if len(raw) == 0 {
+		return AttributeValue{}, false
+	}
+
+	switch raw[0] {
+	case '{', '[':
+		return AttributeValue{StringValue: compactJSON(raw)}, true
+	case '"':
+		var s string
+		json.Unmarshal(raw, &s)
+		return AttributeValue{StringValue: s}, true
+	case 't', 'f':
+		b := raw[0] == 't'
+		return AttributeValue{BoolValue: &b}, true
+	case 'n':
+		// null carries no value
+		return AttributeValue{}, false
+	default:
+		if i, err := strconv.ParseInt(string(raw), 10, 64); err == nil {
+			return AttributeValue{IntValue: &i}, true
+		}
+		if f, err := strconv.ParseFloat(string(raw), 64); err == nil {
+			return AttributeValue{DoubleValue: &f}, true
+		}
+		return AttributeValue{StringValue: string(raw)}, true
+	}
+}
+
+// compactJSON strips insignificant whitespace from valid JSON
+func compactJSON(raw []byte) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return string(raw)
+	}
+	return buf.String()
+}
@@ -0,0 +1,106 @@
+package otlpconvert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// httpClient is shared by all exports so connections to the collector are
+// reused between batches
+var httpClient = &http.Client{Timeout: 60 * time.Second}
+
+// exportHTTP POSTs traces to Config.Endpoint as an OTLP/HTTP request
+// (ExportTraceServiceRequest has the same shape as TracesData). 429, 502, 503
+// and 504 responses and network errors are retried with exponential backoff,
+// honouring Retry-After; other errors fail the batch immediately.
+func (c *Converter) exportHTTP(traces map[string][]*OTLPSpan, batchNum int) {
+	otlpExport, spanCount := buildOTLPExport(traces, c.traceOrder(traces), c.config.SchemaURL)
+
+	var body []byte
+	contentType := "application/x-protobuf"
+	if c.config.HTTPEncoding == "json" {
+		contentType = "application/json"
+		data, err := json.Marshal(otlpExport)
+		if err != nil {
+			c.lostBatches.Add(1)
+			slog.Error("failed to encode OTLP JSON request", "batch", batchNum, "error", err)
+			return
+		}
+		body = data
+	} else {
+		body = MarshalOTLPProto(otlpExport)
+	}
+
+	if c.limiter != nil {
+		c.limiter.wait(spanCount)
+	}
+
+	backoff := writeRetryBackoff
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := c.postOTLP(body, contentType)
+		if err == nil {
+			break
+		}
+		if retryAfter < 0 || attempt >= c.config.WriteRetries {
+			c.lostBatches.Add(1)
+			slog.Error("failed to export batch", "batch", batchNum, "endpoint", c.config.Endpoint, "spans", spanCount, "error", err)
+			return
+		}
+		wait := max(backoff, retryAfter)
+		slog.Warn("export failed, retrying", "batch", batchNum, "attempt", attempt+1, "backoff", wait.String(), "error", err)
+		time.Sleep(wait)
+		backoff *= 2
+	}
+
+	if c.limiter != nil {
+		c.limiter.exported(spanCount)
+	}
+
+	c.statsLock.Lock()
+	c.totalSpans += spanCount
+	c.statsLock.Unlock()
+
+	slog.Info("wrote batch", "format", "http", "spans", spanCount, "batch", batchNum, "endpoint", c.config.Endpoint, "resource_spans", len(otlpExport.ResourceSpans))
+}
+
+// postOTLP sends one request. On failure it returns how long the server asked
+// us to wait (0 if it did not say), or a negative duration if the request must
+// not be retried.
+func (c *Converter) postOTLP(body []byte, contentType string) (time.Duration, error) {
+	req, err := http.NewRequest(http.MethodPost, c.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return -1, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for key, value := range c.config.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Drain a little of the body so the connection can be reused
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return 0, nil
+	}
+
+	err = fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(msg))
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		// Retry-After may also be an HTTP date; only the seconds form is honoured
+		seconds, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+		return time.Duration(seconds) * time.Second, err
+	default:
+		return -1, err
+	}
+}
@@ -0,0 +1,107 @@
+package otlpconvert
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	jaeger "github.com/jaegertracing/jaeger/model"
+)
+
+// traceID64BitKey marks spans whose trace ID was 64-bit in Jaeger
+const traceID64BitKey = "jaeger.trace_id_64bit"
+
+// trueValue backs the BoolValue of marker attributes such as traceID64BitKey
+// and debugFlagKey. It is never modified, so spans may share it.
+var trueValue = true
+
+// idMarshaler is implemented by jaeger.TraceID and jaeger.SpanID
+type idMarshaler interface {
+	MarshalTo(data []byte) (int, error)
+}
+
+// idMarshalTo writes an ID into data. It is the ID's own MarshalTo, which
+// tests replace to make conversion see a failed or short write.
+var idMarshalTo = func(id idMarshaler, data []byte) (int, error) {
+	return id.MarshalTo(data)
+}
+
+// marshalID writes id into b, which must be exactly filled. A short write
+// would leave trailing zero bytes that look like a valid ID, so the byte
+// count is checked as well as the error. what names the ID in errors.
+func marshalID(id idMarshaler, b []byte, what string) error {
+	n, err := idMarshalTo(id, b)
+	if err != nil {
+		return fmt.Errorf("marshal %s: %w", what, err)
+	}
+	if n != len(b) {
+		return fmt.Errorf("marshal %s: wrote %d bytes, want %d", what, n, len(b))
+	}
+	return nil
+}
+
+// marshalTraceID returns the 16-byte form of a Jaeger trace ID
+func marshalTraceID(id *jaeger.TraceID) ([16]byte, error) {
+	var b [16]byte
+	err := marshalID(id, b[:], "trace ID")
+	return b, err
+}
+
+// marshalSpanID returns the 8-byte form of a Jaeger span ID
+func marshalSpanID(id *jaeger.SpanID) ([8]byte, error) {
+	var b [8]byte
+	err := marshalID(id, b[:], "span ID")
+	return b, err
+}
+
+// marshalIDs returns the trace and span ID bytes of a span
+func marshalIDs(span *jaeger.Span) ([16]byte, [8]byte, error) {
+	traceID, err := marshalTraceID(&span.TraceID)
+	if err != nil {
+		return traceID, [8]byte{}, err
+	}
+	spanID, err := marshalSpanID(&span.SpanID)
+	return traceID, spanID, err
+}
+
+// OTLP encodes trace IDs as 32 and span IDs as 16 lowercase hex characters,
+// and treats the all-zero ID as invalid. Every ID written to the output goes
+// through formatTraceID/formatSpanID, and every user-supplied one through
+// normalizeHexID, so widths cannot drift.
+
+// formatTraceID returns the OTLP hex form of a trace ID, or false for the
+// all-zero ID
+func formatTraceID(b [16]byte) (string, bool) {
+	if isZeroID(b[:]) {
+		return "", false
+	}
+	return hex.EncodeToString(b[:]), true
+}
+
+// formatSpanID returns the OTLP hex form of a span ID, or false for the
+// all-zero ID
+func formatSpanID(b [8]byte) (string, bool) {
+	if isZeroID(b[:]) {
+		return "", false
+	}
+	return hex.EncodeToString(b[:]), true
+}
+
+// normalizeHexID converts a hex ID of up to size bytes to its full-width
+// lowercase form, left-padding with zeros as Jaeger UI omits leading ones. It
+// rejects empty, over-long, non-hex and all-zero IDs.
+func normalizeHexID(id string, size int) (string, error) {
+	id = strings.ToLower(strings.TrimSpace(id))
+	if id == "" || len(id) > 2*size {
+		return "", fmt.Errorf("ID %q must be 1 to %d hex characters", id, 2*size)
+	}
+	padded := strings.Repeat("0", 2*size-len(id)) + id
+	b, err := hex.DecodeString(padded)
+	if err != nil {
+		return "", fmt.Errorf("ID %q is not hex", id)
+	}
+	if isZeroID(b) {
+		return "", fmt.Errorf("ID %q is all zeros", id)
+	}
+	return padded, nil
+}
@@ -0,0 +1,241 @@
+package otlpconvert
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	jaeger "github.com/jaegertracing/jaeger/model"
+)
+
+func TestMarshalIDs(t *testing.T) {
+	tests := []struct {
+		name                string
+		traceID             jaeger.TraceID
+		spanID              jaeger.SpanID
+		wantTrace, wantSpan string
+	}{
+		{
+			name:      "128-bit",
+			traceID:   jaeger.NewTraceID(0x0123456789abcdef, 0xfedcba9876543210),
+			spanID:    jaeger.NewSpanID(0x1122334455667788),
+			wantTrace: "0123456789abcdeffedcba9876543210",
+			wantSpan:  "1122334455667788",
+		},
+		{
+			name:      "64-bit trace ID is left-padded",
+			traceID:   jaeger.NewTraceID(0, 0xff),
+			spanID:    jaeger.NewSpanID(1),
+			wantTrace: "000000000000000000000000000000ff",
+			wantSpan:  "0000000000000001",
+		},
+		{
+			name:      "all bits set",
+			traceID:   jaeger.NewTraceID(^uint64(0), ^uint64(0)),
+			spanID:    jaeger.NewSpanID(^uint64(0)),
+			wantTrace: "ffffffffffffffffffffffffffffffff",
+			wantSpan:  "ffffffffffffffff",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			traceID, spanID, err := marshalIDs(&jaeger.Span{TraceID: tt.traceID, SpanID: tt.spanID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := hex.EncodeToString(traceID[:]); got != tt.wantTrace {
+				t.Errorf("trace ID = %s, want %s", got, tt.wantTrace)
+			}
+			if got := hex.EncodeToString(spanID[:]); got != tt.wantSpan {
+				t.Errorf("span ID = %s, want %s", got, tt.wantSpan)
+			}
+
+			// The converted span carries the same IDs
+			span := testSpan(1)
+			span.TraceID, span.SpanID = tt.traceID, tt.spanID
+			otlp := New(Config{}).ConvertJaegerSpan(span)
+			if otlp.TraceID != tt.wantTrace || otlp.SpanID != tt.wantSpan {
+				t.Errorf("converted IDs = %s/%s, want %s/%s", otlp.TraceID, otlp.SpanID, tt.wantTrace, tt.wantSpan)
+			}
+			if hex.EncodeToString(otlp.TraceIDBytes[:]) != tt.wantTrace || hex.EncodeToString(otlp.SpanIDBytes[:]) != tt.wantSpan {
+				t.Errorf("converted ID bytes = %x/%x, want %s/%s", otlp.TraceIDBytes, otlp.SpanIDBytes, tt.wantTrace, tt.wantSpan)
+			}
+		})
+	}
+}
+
+func TestNormalizeHexID(t *testing.T) {
+	tests := []struct {
+		id      string
+		size    int
+		want    string
+		wantErr bool
+	}{
+		{id: "abc", size: 16, want: "00000000000000000000000000000abc"},
+		{id: "1", size: 8, want: "0000000000000001"},
+		{id: " ABCDEF ", size: 8, want: "0000000000abcdef"},
+		{id: "0123456789abcdef0123456789abcdef", size: 16, want: "0123456789abcdef0123456789abcdef"},
+		{id: "0123456789abcdef0", size: 8, wantErr: true},
+		{id: "", size: 16, wantErr: true},
+		{id: "xyz", size: 16, wantErr: true},
+		{id: "0", size: 16, wantErr: true},
+		{id: "0000000000000000", size: 8, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := normalizeHexID(tt.id, tt.size)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("normalizeHexID(%q, %d) error = %v, want error %v", tt.id, tt.size, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("normalizeHexID(%q, %d) = %q, want %q", tt.id, tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestZeroIDs(t *testing.T) {
+	tests := []struct {
+		name         string
+		traceID      jaeger.TraceID
+		spanID       jaeger.SpanID
+		refs         []jaeger.SpanRef
+		dropped      bool
+		wantParent   string
+		wantLinks    int
+		droppedLinks uint32
+	}{
+		{name: "zero trace ID", traceID: jaeger.NewTraceID(0, 0), spanID: 1, dropped: true},
+		{name: "zero span ID", traceID: jaeger.NewTraceID(1, 1), spanID: 0, dropped: true},
+		{
+			name:    "short parent ID padded",
+			traceID: jaeger.NewTraceID(1, 1), spanID: 2,
+			refs:       []jaeger.SpanRef{jaeger.NewChildOfRef(jaeger.NewTraceID(1, 1), 1)},
+			wantParent: "0000000000000001",
+		},
+		{
+			name:    "zero parent leaves a root",
+			traceID: jaeger.NewTraceID(1, 1), spanID: 2,
+			refs: []jaeger.SpanRef{jaeger.NewChildOfRef(jaeger.NewTraceID(1, 1), 0)},
+		},
+		{
+			name:    "zero link span ID dropped",
+			traceID: jaeger.NewTraceID(1, 1), spanID: 2,
+			refs:         []jaeger.SpanRef{jaeger.NewFollowsFromRef(jaeger.NewTraceID(1, 1), 0), jaeger.NewFollowsFromRef(jaeger.NewTraceID(1, 1), 3)},
+			wantLinks:    1,
+			droppedLinks: 1,
+		},
+		{
+			name:    "zero link trace ID dropped",
+			traceID: jaeger.NewTraceID(1, 1), spanID: 2,
+			refs:         []jaeger.SpanRef{jaeger.NewFollowsFromRef(jaeger.NewTraceID(0, 0), 3)},
+			droppedLinks: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			span := testSpan(1)
+			span.TraceID, span.SpanID, span.References = tt.traceID, tt.spanID, tt.refs
+			otlp := New(Config{}).ConvertJaegerSpan(span)
+			if tt.dropped {
+				if otlp != nil {
+					t.Errorf("span kept with IDs %s/%s, want it dropped", otlp.TraceID, otlp.SpanID)
+				}
+				return
+			}
+			if otlp == nil {
+				t.Fatal("span dropped")
+			}
+			if otlp.ParentSpanID != tt.wantParent {
+				t.Errorf("parent = %q, want %q", otlp.ParentSpanID, tt.wantParent)
+			}
+			if len(otlp.Links) != tt.wantLinks || otlp.DroppedLinksCount != tt.droppedLinks {
+				t.Errorf("links = %d (dropped %d), want %d (dropped %d)", len(otlp.Links), otlp.DroppedLinksCount, tt.wantLinks, tt.droppedLinks)
+			}
+			for _, link := range otlp.Links {
+				if len(link.TraceID) != 32 || len(link.SpanID) != 16 {
+					t.Errorf("link IDs %q/%q not full width", link.TraceID, link.SpanID)
+				}
+			}
+		})
+	}
+}
+
+// TestTraceID64BitMarker checks only Jaeger spans with a 64-bit trace ID are
+// marked, not Zipkin spans with one
+func TestTraceID64BitMarker(t *testing.T) {
+	c := New(Config{})
+	entries := New(Config{InputFormat: "zipkin", ValueEncoding: "raw"})
+	var fromEntry *OTLPSpan
+	if spans := entries.parseEntry(BadgerEntry{Value: []byte(`{"traceId":"0000000000000001","id":"0000000000000002","timestamp":1700000000000000}`)}); len(spans) == 1 {
+		fromEntry = spans[0]
+	}
+
+	short := testSpan(1)
+	short.TraceID = jaeger.NewTraceID(0, 1)
+	tests := []struct {
+		name string
+		span *OTLPSpan
+		want bool
+	}{
+		{name: "jaeger 64-bit", span: c.ConvertJaegerSpan(short), want: true},
+		{name: "jaeger 128-bit", span: c.ConvertJaegerSpan(testSpan(1))},
+		{name: "zipkin 64-bit", span: c.ConvertZipkinSpan(&ZipkinSpan{TraceID: "0000000000000001", ID: "0000000000000002", Timestamp: 1700000000000000})},
+		{name: "zipkin entry 64-bit", span: fromEntry},
+		{name: "zipkin 128-bit", span: c.ConvertZipkinSpan(&ZipkinSpan{TraceID: "00000000000000010000000000000001", ID: "0000000000000002", Timestamp: 1700000000000000})},
+	}
+	for _, tt := range tests {
+		if tt.span == nil {
+			t.Fatalf("%s: span not converted", tt.name)
+		}
+		if _, ok := attr(tt.span.Attributes, traceID64BitKey); ok != tt.want {
+			t.Errorf("%s: %s present = %v, want %v", tt.name, traceID64BitKey, ok, tt.want)
+		}
+	}
+}
+
+func TestMarshalIDErrors(t *testing.T) {
+	traceID := jaeger.NewTraceID(1, 2)
+	if err := marshalID(&traceID, make([]byte, 8), "trace ID"); err == nil {
+		t.Error("marshal into a short buffer: no error")
+	}
+	if err := marshalID(&traceID, make([]byte, 20), "trace ID"); err == nil || !strings.Contains(err.Error(), "wrote 16 bytes, want 20") {
+		t.Errorf("marshal into a long buffer: error = %v, want a short write", err)
+	}
+}
+
+// TestMalformedIDsDropped checks a span whose IDs fail to marshal, or
+// marshal short, is dropped and counted as malformed
+func TestMalformedIDsDropped(t *testing.T) {
+	tests := []struct {
+		name      string
+		marshalTo func(id idMarshaler, data []byte) (int, error)
+	}{
+		{"error", func(id idMarshaler, data []byte) (int, error) {
+			return 0, errors.New("boom")
+		}},
+		{"short write", func(id idMarshaler, data []byte) (int, error) {
+			n, err := id.MarshalTo(data)
+			return n - 1, err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := marshalEntry(t, testSpan(1))
+			previous := idMarshalTo
+			idMarshalTo = tt.marshalTo
+			t.Cleanup(func() { idMarshalTo = previous })
+
+			c := New(Config{ValueEncoding: "raw"})
+			if span := c.ConvertJaegerSpan(testSpan(1)); span != nil {
+				t.Error("ConvertJaegerSpan returned a span")
+			}
+			if spans := c.parseEntry(entry); len(spans) != 0 {
+				t.Errorf("parseEntry returned %d spans, want 0", len(spans))
+			}
+			if got := c.MalformedIDs(); got != 2 {
+				t.Errorf("malformed IDs = %d, want 2", got)
+			}
+		})
+	}
+}
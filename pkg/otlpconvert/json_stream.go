@@ -0,0 +1,101 @@
+package otlpconvert
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// streamOTLPJSON writes traces to w as an OTLP JSON TracesData grouped by
+// resource, like buildOTLPExport, and returns the number of ResourceSpans
+// written. Each ResourceSpans is encoded as soon as it is built and then
+// dropped, so neither the whole export nor its encoding is held in memory.
+func streamOTLPJSON(w io.Writer, traces map[string][]*OTLPSpan, order []string, schemaURL string, pretty bool) (int, error) {
+	s := resourceSpansStream{w: w, pretty: pretty}
+	if _, err := eachResourceSpans(traces, order, schemaURL, s.add); err != nil {
+		return s.n, err
+	}
+	return s.n, s.close()
+}
+
+// streamOTLPExport writes export to w as OTLP JSON one ResourceSpans at a
+// time, so only a single resource's encoding is held in memory rather than
+// the whole batch's. The bytes are the same as json.Encoder.Encode produces
+// for the export, with SetIndent("", "  ") when pretty is set.
+func streamOTLPExport(w io.Writer, export *OTLPExport, pretty bool) error {
+	// Nothing to stream; leaves the null/[] distinction to encoding/json
+	if len(export.ResourceSpans) == 0 {
+		return encodeEmptyExport(w, export, pretty)
+	}
+
+	s := resourceSpansStream{w: w, pretty: pretty}
+	for i := range export.ResourceSpans {
+		if err := s.add(&export.ResourceSpans[i]); err != nil {
+			return err
+		}
+	}
+	return s.close()
+}
+
+// resourceSpansStream writes the elements of a TracesData's resourceSpans
+// array as they are added, with the document opened on the first and closed
+// by close
+type resourceSpansStream struct {
+	w      io.Writer
+	pretty bool
+	n      int // ResourceSpans written so far
+}
+
+// add encodes one ResourceSpans into the stream
+func (s *resourceSpansStream) add(rs *ResourceSpans) error {
+	open, sep := `{"resourceSpans":[`, ","
+	if s.pretty {
+		open, sep = "{\n  \"resourceSpans\": [\n    ", ",\n    "
+	}
+	prefix := sep
+	if s.n == 0 {
+		prefix = open
+	}
+	if _, err := io.WriteString(s.w, prefix); err != nil {
+		return err
+	}
+
+	var data []byte
+	var err error
+	if s.pretty {
+		// Elements sit two levels deep in the indented document
+		data, err = json.MarshalIndent(rs, "    ", "  ")
+	} else {
+		data, err = json.Marshal(rs)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := s.w.Write(data); err != nil {
+		return err
+	}
+	s.n++
+	return nil
+}
+
+// close ends the document, writing an empty export if nothing was added
+func (s *resourceSpansStream) close() error {
+	if s.n == 0 {
+		return encodeEmptyExport(s.w, &OTLPExport{ResourceSpans: []ResourceSpans{}}, s.pretty)
+	}
+	end := "]}\n"
+	if s.pretty {
+		end = "\n  ]\n}\n"
+	}
+	_, err := io.WriteString(s.w, end)
+	return err
+}
+
+// encodeEmptyExport writes an export without ResourceSpans with
+// encoding/json
+func encodeEmptyExport(w io.Writer, export *OTLPExport, pretty bool) error {
+	encoder := json.NewEncoder(w)
+	if pretty {
+		encoder.SetIndent("", "  ")
+	}
+	return encoder.Encode(export)
+}
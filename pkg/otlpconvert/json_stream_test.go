@@ -0,0 +1,62 @@
+package otlpconvert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	jaeger "github.com/jaegertracing/jaeger/model"
+)
+
+func TestStreamOTLPJSON(t *testing.T) {
+	c := New(Config{})
+	traces := make(map[string][]*OTLPSpan)
+	var order []string
+	for i := 1; i <= 12; i++ {
+		span := testSpan(uint64(i))
+		span.TraceID = jaeger.NewTraceID(1, uint64(i%3+1))
+		span.Process = &jaeger.Process{ServiceName: fmt.Sprintf("svc%d", i%4)}
+		otlp := c.ConvertJaegerSpan(span)
+		if _, ok := traces[otlp.TraceID]; !ok {
+			order = append(order, otlp.TraceID)
+		}
+		traces[otlp.TraceID] = append(traces[otlp.TraceID], otlp)
+	}
+
+	tests := []struct {
+		name   string
+		traces map[string][]*OTLPSpan
+		order  []string
+	}{
+		{"resources", traces, order},
+		{"empty", map[string][]*OTLPSpan{}, nil},
+	}
+	for _, tt := range tests {
+		for _, pretty := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/pretty=%v", tt.name, pretty), func(t *testing.T) {
+				export, _ := buildOTLPExport(tt.traces, tt.order, "https://example.com/schema")
+				var want bytes.Buffer
+				encoder := json.NewEncoder(&want)
+				if pretty {
+					encoder.SetIndent("", "  ")
+				}
+				if err := encoder.Encode(export); err != nil {
+					t.Fatal(err)
+				}
+
+				var got bytes.Buffer
+				n, err := streamOTLPJSON(&got, tt.traces, tt.order, "https://example.com/schema", pretty)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got.String() != want.String() {
+					t.Errorf("streamed output differs from encoding/json:\ngot:  %s\nwant: %s", got.String(), want.String())
+				}
+				if n != len(export.ResourceSpans) {
+					t.Errorf("resource spans = %d, want %d", n, len(export.ResourceSpans))
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package otlpconvert
+
+import (
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// latencyHistogram is a lock-free histogram with power-of-two nanosecond
+// buckets. Bucket i counts durations in [2^(i-1), 2^i) ns, so percentiles are
+// accurate to within a factor of two, which is plenty for telling a 2µs
+// protobuf parse from a 200µs one.
+type latencyHistogram struct {
+	buckets [64]atomic.Int64
+	count   atomic.Int64
+}
+
+func (h *latencyHistogram) record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	h.buckets[bits.Len64(uint64(d))&63].Add(1)
+	h.count.Add(1)
+}
+
+// percentile returns the upper bound of the bucket holding the p-th
+// percentile (0 < p <= 100), or 0 if nothing was recorded
+func (h *latencyHistogram) percentile(p float64) time.Duration {
+	total := h.count.Load()
+	if total == 0 {
+		return 0
+	}
+	rank := int64(float64(total)*p/100 + 0.5)
+	if rank < 1 {
+		rank = 1
+	}
+	var seen int64
+	for i := range h.buckets {
+		seen += h.buckets[i].Load()
+		if seen >= rank {
+			return time.Duration(uint64(1)<<i - 1)
+		}
+	}
+	return time.Duration(1<<63 - 1)
+}
@@ -0,0 +1,86 @@
+package otlpconvert
+
+import (
+	"sort"
+	"unicode/utf8"
+
+	jaeger "github.com/jaegertracing/jaeger/model"
+)
+
+// applyAttributeLimits enforces MaxAttrValueLen and MaxAttrsPerSpan on a span
+// or event's attributes, the way OTel SDK attribute limits do: string values
+// (including those inside arrays and key-value lists) are cut to at most MaxAttrValueLen bytes on
+// a UTF-8 boundary, and attributes beyond MaxAttrsPerSpan are dropped. It
+// returns the trimmed attributes and how many were dropped; truncated values
+// are not counted as dropped.
+func (c *Converter) applyAttributeLimits(attrs []Attribute) ([]Attribute, uint32) {
+	if maxLen := c.config.MaxAttrValueLen; maxLen > 0 {
+		for i := range attrs {
+			truncateValue(&attrs[i].Value, maxLen)
+		}
+	}
+
+	var dropped uint32
+	if limit := c.config.MaxAttrsPerSpan; limit > 0 && len(attrs) > limit {
+		dropped = uint32(len(attrs) - limit)
+		clear(attrs[limit:])
+		attrs = attrs[:limit]
+	}
+	return attrs, dropped
+}
+
+// truncateValue cuts string values longer than maxLen bytes, including those
+// nested in arrays and key-value lists
+func truncateValue(v *AttributeValue, maxLen int) {
+	if len(v.StringValue) > maxLen {
+		v.StringValue = truncateUTF8(v.StringValue, maxLen)
+	}
+	if v.ArrayValue != nil {
+		for i := range v.ArrayValue.Values {
+			truncateValue(&v.ArrayValue.Values[i], maxLen)
+		}
+	}
+	if v.KvlistValue != nil {
+		for i := range v.KvlistValue.Values {
+			truncateValue(&v.KvlistValue.Values[i].Value, maxLen)
+		}
+	}
+}
+
+// truncateUTF8 returns at most maxLen bytes of s without splitting a rune
+func truncateUTF8(s string, maxLen int) string {
+	cut := maxLen
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut]
+}
+
+// applyEventLimit enforces MaxEventsPerSpan on a span's logs before they are
+// converted to events. Over the limit, the logs are put in time order and
+// either the earliest are kept (EventSampling "first") or the kept ones are
+// spread evenly from the first log to the last ("even"). It returns the logs
+// to convert and how many were dropped, for droppedEventsCount; the span's
+// own Logs are left untouched.
+func (c *Converter) applyEventLimit(logs []jaeger.Log) ([]jaeger.Log, uint32) {
+	limit := c.config.MaxEventsPerSpan
+	if limit <= 0 || len(logs) <= limit {
+		return logs, 0
+	}
+
+	sorted := make([]jaeger.Log, len(logs))
+	copy(sorted, logs)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+	dropped := uint32(len(logs) - limit)
+	if c.config.EventSampling != "even" || limit == 1 {
+		return sorted[:limit], dropped
+	}
+
+	kept := make([]jaeger.Log, limit)
+	for i := range kept {
+		kept[i] = sorted[i*(len(sorted)-1)/(limit-1)]
+	}
+	return kept, dropped
+}
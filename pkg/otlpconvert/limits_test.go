@@ -0,0 +1,65 @@
+package otlpconvert
+
+import (
+	"encoding/json"
+	"testing"
+
+	jaeger "github.com/jaegertracing/jaeger/model"
+)
+
+func TestTruncateValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value AttributeValue
+		want  string // JSON of the truncated value
+	}{
+		{name: "string", value: AttributeValue{StringValue: "abcdefgh"}, want: `{"stringValue":"abcd"}`},
+		{name: "short string", value: AttributeValue{StringValue: "ab"}, want: `{"stringValue":"ab"}`},
+		{name: "rune boundary", value: AttributeValue{StringValue: "abcé"}, want: `{"stringValue":"abc"}`},
+		{
+			name:  "array",
+			value: AttributeValue{ArrayValue: &ArrayValue{Values: []AttributeValue{{StringValue: "abcdefgh"}, {StringValue: "x"}}}},
+			want:  `{"arrayValue":{"values":[{"stringValue":"abcd"},{"stringValue":"x"}]}}`,
+		},
+		{
+			name: "kvlist",
+			value: AttributeValue{KvlistValue: &KeyValueList{Values: []Attribute{
+				{Key: "long-key-is-kept", Value: AttributeValue{StringValue: "abcdefgh"}},
+			}}},
+			want: `{"kvlistValue":{"values":[{"key":"long-key-is-kept","value":{"stringValue":"abcd"}}]}}`,
+		},
+		{
+			name: "nested kvlist in array",
+			value: AttributeValue{ArrayValue: &ArrayValue{Values: []AttributeValue{{KvlistValue: &KeyValueList{Values: []Attribute{
+				{Key: "k", Value: AttributeValue{KvlistValue: &KeyValueList{Values: []Attribute{
+					{Key: "deep", Value: AttributeValue{StringValue: "abcdefgh"}},
+				}}}},
+			}}}}}},
+			want: `{"arrayValue":{"values":[{"kvlistValue":{"values":[{"key":"k","value":{"kvlistValue":{"values":[{"key":"deep","value":{"stringValue":"abcd"}}]}}}]}}]}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			truncateValue(&tt.value, 4)
+			got, _ := json.Marshal(tt.value)
+			if string(got) != tt.want {
+				t.Errorf("truncated =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestTruncateFlattenedKvlist checks -max-attr-value-len reaches the values
+// of a JSON tag kept as a kvlist
+func TestTruncateFlattenedKvlist(t *testing.T) {
+	c := New(Config{FlattenJSONTags: true, JSONTagStyle: "kvlist", MaxAttrValueLen: 4})
+	span := testSpan(1)
+	span.Tags = []jaeger.KeyValue{jaeger.String("payload", `{"body":"abcdefgh","inner":{"text":"ijklmnop"}}`)}
+	got, _ := json.Marshal(c.ConvertJaegerSpan(span).Attributes)
+	want := `[{"key":"payload","value":{"kvlistValue":{"values":[` +
+		`{"key":"body","value":{"stringValue":"abcd"}},` +
+		`{"key":"inner","value":{"kvlistValue":{"values":[{"key":"text","value":{"stringValue":"ijkl"}}]}}}]}}}]`
+	if string(got) != want {
+		t.Errorf("attributes =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,15 @@
+package otlpconvert
+
+import "runtime"
+
+// memoryCheckInterval is how many collected spans pass between heap checks
+// for Config.MaxMemoryMB; runtime.ReadMemStats stops the world, so it is not
+// called per span
+const memoryCheckInterval = 10000
+
+// overMemoryLimit reports whether the live heap exceeds Config.MaxMemoryMB
+func (c *Converter) overMemoryLimit() bool {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc > uint64(c.config.MaxMemoryMB)<<20
+}
@@ -0,0 +1,35 @@
+package otlpconvert
+
+import (
+	"sort"
+	"strconv"
+)
+
+// traceOrder returns the trace IDs in the order they are written: sorted
+// when Config.Deterministic is set, Go map order (random) otherwise
+func (c *Converter) traceOrder(traces map[string][]*OTLPSpan) []string {
+	order := make([]string, 0, len(traces))
+	for traceID := range traces {
+		order = append(order, traceID)
+	}
+	if c.config.Deterministic {
+		sort.Strings(order)
+	}
+	return order
+}
+
+// sortTraceSpans orders the spans of every trace by start time, breaking
+// ties by span ID, so a trace is written the same way whatever order the
+// workers delivered its spans in
+func sortTraceSpans(traces map[string][]*OTLPSpan) {
+	for _, spans := range traces {
+		sort.Slice(spans, func(i, j int) bool {
+			si, _ := strconv.ParseInt(spans[i].StartTimeUnixNano, 10, 64)
+			sj, _ := strconv.ParseInt(spans[j].StartTimeUnixNano, 10, 64)
+			if si != sj {
+				return si < sj
+			}
+			return spans[i].SpanID < spans[j].SpanID
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package otlpconvert
+
+import jaeger "github.com/jaegertracing/jaeger/model"
+
+// otelStatusCodes maps the otel.status_code tag written by OpenTelemetry's
+// Jaeger exporter back to OTLP status codes
+var otelStatusCodes = map[string]string{
+	"OK":    "STATUS_CODE_OK",
+	"ERROR": "STATUS_CODE_ERROR",
+	"UNSET": "STATUS_CODE_UNSET",
+}
+
+// hasOTelMarkers reports whether a span was produced by translating an OTLP
+// span to Jaeger: the OpenTelemetry exporter records the status code and the
+// instrumentation scope as otel.* tags, which native Jaeger clients never set
+func hasOTelMarkers(tags []jaeger.KeyValue) bool {
+	for _, tag := range tags {
+		switch tag.Key {
+		case "otel.status_code", "otel.scope.name", "otel.library.name":
+			return true
+		}
+	}
+	return false
+}
+
+// applyNativeTag maps a tag of an OTLP-origin span back onto the OTLP field
+// it was derived from, so it is not kept as an attribute as well. status
+// collects otel.status_code and otel.status_description, which take
+// precedence over the error tags once all tags are seen. It returns false for
+// tags that are ordinary attributes.
+func applyNativeTag(otlp *OTLPSpan, status *Status, tag jaeger.KeyValue, attr Attribute) bool {
+	switch tag.Key {
+	case "otel.status_code":
+		status.Code = otelStatusCodes[tag.VStr]
+	case "otel.status_description":
+		status.Message = attr.Value.StringValue
+	case "otel.scope.name", "otel.library.name":
+		otlp.Scope.Name = tag.VStr
+	case "otel.scope.version", "otel.library.version":
+		otlp.Scope.Version = tag.VStr
+	case "span.kind":
+		otlp.Kind = otlpSpanKind(tag.VStr)
+	case traceStateKey:
+		otlp.TraceState = attr.Value.StringValue
+	case "error":
+		// Set by the exporter alongside otel.status_code=ERROR
+		if tag.VBool || tag.VStr == "true" {
+			otlp.Status.Code = "STATUS_CODE_ERROR"
+		}
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,143 @@
+package otlpconvert
+
+// OTLPSpan represents a complete OTLP span structure
+type OTLPSpan struct {
+	TraceID           string      `json:"traceId"`
+	SpanID            string      `json:"spanId"`
+	TraceState        string      `json:"traceState,omitempty"` // W3C tracestate, from the w3c.tracestate tag
+	ParentSpanID      string      `json:"parentSpanId,omitempty"`
+	Name              string      `json:"name"`
+	Kind              string      `json:"kind"`
+	StartTimeUnixNano string      `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string      `json:"endTimeUnixNano"`
+	Attributes        []Attribute `json:"attributes"`
+	Events            []Event     `json:"events"`
+	Status            Status      `json:"status"`
+	TraceFlags        string      `json:"traceFlags,omitempty"`
+	Links             []Link      `json:"links,omitempty"`
+
+	// Counts of attributes, events and links the converter dropped from this
+	// span, so consumers know it was trimmed
+	DroppedAttributesCount uint32 `json:"droppedAttributesCount,omitempty"`
+	DroppedEventsCount     uint32 `json:"droppedEventsCount,omitempty"`
+	DroppedLinksCount      uint32 `json:"droppedLinksCount,omitempty"`
+
+	// Resource holds the process attributes (service.name and process tags)
+	// that belong on the enclosing ResourceSpans rather than on the span itself
+	Resource []Attribute `json:"-"`
+
+	// Scope is the instrumentation scope, set only from the otel.scope.* tags
+	// of OTLP-origin spans (Config.OTelNativeTags); it belongs on the
+	// enclosing ScopeSpans
+	Scope InstrumentationScope `json:"-"`
+
+	// sharedResource marks Resource as owned by the process cache, so it is
+	// neither modified nor recycled with the span
+	sharedResource bool
+
+	// entrySeq is the BadgerEntry.Seq of the entry the span was read from
+	entrySeq int64
+
+	// missingTimestamp marks a Zipkin span that had no timestamp, whose
+	// start was clamped to the epoch
+	missingTimestamp bool
+
+	// Raw IDs behind TraceID/SpanID, kept for compact binary Arrow columns
+	TraceIDBytes [16]byte `json:"-"`
+	SpanIDBytes  [8]byte  `json:"-"`
+}
+
+// Link represents an OTLP link (for distributed tracing)
+type Link struct {
+	TraceID    string      `json:"traceId"`
+	SpanID     string      `json:"spanId"`
+	Attributes []Attribute `json:"attributes,omitempty"`
+}
+
+// Attribute represents an OTLP attribute (key-value pair)
+type Attribute struct {
+	Key   string         `json:"key"`
+	Value AttributeValue `json:"value"`
+}
+
+// AttributeValue represents the value of an attribute
+type AttributeValue struct {
+	StringValue string        `json:"stringValue,omitempty"`
+	BoolValue   *bool         `json:"boolValue,omitempty"`
+	IntValue    *int64        `json:"intValue,omitempty"`
+	DoubleValue *float64      `json:"doubleValue,omitempty"`
+	BytesValue  string        `json:"bytesValue,omitempty"` // base64-encoded
+	ArrayValue  *ArrayValue   `json:"arrayValue,omitempty"`
+	KvlistValue *KeyValueList `json:"kvlistValue,omitempty"`
+}
+
+// ArrayValue represents an OTLP array attribute value
+type ArrayValue struct {
+	Values []AttributeValue `json:"values"`
+}
+
+// KeyValueList represents an OTLP kvlist attribute value (a nested map)
+type KeyValueList struct {
+	Values []Attribute `json:"values"`
+}
+
+// Event represents an OTLP event (log)
+type Event struct {
+	TimeUnixNano           string      `json:"timeUnixNano"`
+	Name                   string      `json:"name"`
+	Attributes             []Attribute `json:"attributes"`
+	DroppedAttributesCount uint32      `json:"droppedAttributesCount,omitempty"`
+}
+
+// Status represents the status of a span
+type Status struct {
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// ResourceSpans represents OTLP ResourceSpans structure
+type ResourceSpans struct {
+	Resource   Resource     `json:"resource"`
+	ScopeSpans []ScopeSpans `json:"scopeSpans"`
+	SchemaURL  string       `json:"schemaUrl,omitempty"` // Config.SchemaURL
+}
+
+// Resource represents OTLP Resource
+type Resource struct {
+	Attributes []Attribute `json:"attributes"`
+}
+
+// ScopeSpans represents OTLP ScopeSpans
+type ScopeSpans struct {
+	Scope     *InstrumentationScope `json:"scope,omitempty"`
+	Spans     []*OTLPSpan           `json:"spans"`
+	SchemaURL string                `json:"schemaUrl,omitempty"` // Config.SchemaURL
+}
+
+// InstrumentationScope represents an OTLP InstrumentationScope
+type InstrumentationScope struct {
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+// scopeSpans returns the ScopeSpans of rs for scope, adding it if needed.
+// Spans without a scope share one ScopeSpans that has none.
+func (rs *ResourceSpans) scopeSpans(scope InstrumentationScope) *ScopeSpans {
+	for i := range rs.ScopeSpans {
+		ss := &rs.ScopeSpans[i]
+		if (ss.Scope == nil && scope == InstrumentationScope{}) || (ss.Scope != nil && *ss.Scope == scope) {
+			return ss
+		}
+	}
+	ss := ScopeSpans{}
+	if scope != (InstrumentationScope{}) {
+		ss.Scope = &scope
+	}
+	rs.ScopeSpans = append(rs.ScopeSpans, ss)
+	return &rs.ScopeSpans[len(rs.ScopeSpans)-1]
+}
+
+// OTLPExport represents the top-level OTLP export structure
+type OTLPExport struct {
+	ResourceSpans []ResourceSpans `json:"resourceSpans"`
+}
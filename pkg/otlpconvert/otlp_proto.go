@@ -0,0 +1,276 @@
+package otlpconvert
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"math"
+	"strconv"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+// Field numbers from opentelemetry/proto/trace/v1/trace.proto and
+// opentelemetry/proto/common/v1/common.proto. The encoder below writes the
+// OTLP TracesData wire format directly, without generated message types.
+const (
+	tracesDataResourceSpans = 1
+
+	resourceSpansResource   = 1
+	resourceSpansScopeSpans = 2
+	resourceSpansSchemaURL  = 3
+
+	resourceAttributes = 1
+
+	scopeSpansScope     = 1
+	scopeSpansSpans     = 2
+	scopeSpansSchemaURL = 3
+
+	scopeName    = 1
+	scopeVersion = 2
+
+	spanTraceID      = 1
+	spanSpanID       = 2
+	spanTraceState   = 3
+	spanParentSpanID = 4
+	spanName         = 5
+	spanKind         = 6
+	spanStartTime    = 7
+	spanEndTime      = 8
+	spanAttributes   = 9
+	spanDroppedAttrs = 10
+	spanEvents       = 11
+	spanDroppedEvts  = 12
+	spanLinks        = 13
+	spanDroppedLinks = 14
+	spanStatus       = 15
+	spanFlags        = 16
+
+	eventTime         = 1
+	eventName         = 2
+	eventAttributes   = 3
+	eventDroppedAttrs = 4
+
+	linkTraceID    = 1
+	linkSpanID     = 2
+	linkAttributes = 4
+
+	statusMessage = 2
+	statusCode    = 3
+
+	keyValueKey   = 1
+	keyValueValue = 2
+
+	anyValueString = 1
+	anyValueBool   = 2
+	anyValueInt    = 3
+	anyValueDouble = 4
+	anyValueArray  = 5
+	anyValueKvlist = 6
+	anyValueBytes  = 7
+
+	arrayValueValues   = 1
+	keyValueListValues = 1
+)
+
+var spanKindValues = map[string]uint64{
+	"SPAN_KIND_UNSPECIFIED": 0,
+	"SPAN_KIND_INTERNAL":    1,
+	"SPAN_KIND_SERVER":      2,
+	"SPAN_KIND_CLIENT":      3,
+	"SPAN_KIND_PRODUCER":    4,
+	"SPAN_KIND_CONSUMER":    5,
+}
+
+var statusCodeValues = map[string]uint64{
+	"STATUS_CODE_UNSET": 0,
+	"STATUS_CODE_OK":    1,
+	"STATUS_CODE_ERROR": 2,
+}
+
+// MarshalOTLPProto encodes an export as an OTLP TracesData protobuf message
+func MarshalOTLPProto(export OTLPExport) []byte {
+	b := proto.NewBuffer(nil)
+	for _, rs := range export.ResourceSpans {
+		writeMessage(b, tracesDataResourceSpans, marshalResourceSpans(rs))
+	}
+	return b.Bytes()
+}
+
+func marshalResourceSpans(rs ResourceSpans) []byte {
+	b := proto.NewBuffer(nil)
+
+	resource := proto.NewBuffer(nil)
+	for _, attr := range rs.Resource.Attributes {
+		writeMessage(resource, resourceAttributes, marshalKeyValue(attr))
+	}
+	writeMessage(b, resourceSpansResource, resource.Bytes())
+
+	for _, ss := range rs.ScopeSpans {
+		scope := proto.NewBuffer(nil)
+		if ss.Scope != nil {
+			s := proto.NewBuffer(nil)
+			writeString(s, scopeName, ss.Scope.Name)
+			writeString(s, scopeVersion, ss.Scope.Version)
+			writeMessage(scope, scopeSpansScope, s.Bytes())
+		}
+		for _, span := range ss.Spans {
+			writeMessage(scope, scopeSpansSpans, marshalSpan(span))
+		}
+		writeString(scope, scopeSpansSchemaURL, ss.SchemaURL)
+		writeMessage(b, resourceSpansScopeSpans, scope.Bytes())
+	}
+	writeString(b, resourceSpansSchemaURL, rs.SchemaURL)
+
+	return b.Bytes()
+}
+
+func marshalSpan(span *OTLPSpan) []byte {
+	b := proto.NewBuffer(nil)
+
+	writeHexBytes(b, spanTraceID, span.TraceID)
+	writeHexBytes(b, spanSpanID, span.SpanID)
+	writeString(b, spanTraceState, span.TraceState)
+	writeHexBytes(b, spanParentSpanID, span.ParentSpanID)
+	writeString(b, spanName, span.Name)
+	writeVarint(b, spanKind, spanKindValues[span.Kind])
+	writeFixed64(b, spanStartTime, parseUnixNano(span.StartTimeUnixNano))
+	writeFixed64(b, spanEndTime, parseUnixNano(span.EndTimeUnixNano))
+
+	for _, attr := range span.Attributes {
+		writeMessage(b, spanAttributes, marshalKeyValue(attr))
+	}
+	writeVarint(b, spanDroppedAttrs, uint64(span.DroppedAttributesCount))
+
+	for _, event := range span.Events {
+		e := proto.NewBuffer(nil)
+		writeFixed64(e, eventTime, parseUnixNano(event.TimeUnixNano))
+		writeString(e, eventName, event.Name)
+		for _, attr := range event.Attributes {
+			writeMessage(e, eventAttributes, marshalKeyValue(attr))
+		}
+		writeVarint(e, eventDroppedAttrs, uint64(event.DroppedAttributesCount))
+		writeMessage(b, spanEvents, e.Bytes())
+	}
+	writeVarint(b, spanDroppedEvts, uint64(span.DroppedEventsCount))
+
+	for _, link := range span.Links {
+		l := proto.NewBuffer(nil)
+		writeHexBytes(l, linkTraceID, link.TraceID)
+		writeHexBytes(l, linkSpanID, link.SpanID)
+		for _, attr := range link.Attributes {
+			writeMessage(l, linkAttributes, marshalKeyValue(attr))
+		}
+		writeMessage(b, spanLinks, l.Bytes())
+	}
+	writeVarint(b, spanDroppedLinks, uint64(span.DroppedLinksCount))
+
+	status := proto.NewBuffer(nil)
+	writeString(status, statusMessage, span.Status.Message)
+	writeVarint(status, statusCode, statusCodeValues[span.Status.Code])
+	writeMessage(b, spanStatus, status.Bytes())
+
+	if flags, err := strconv.ParseUint(span.TraceFlags, 16, 8); err == nil && flags != 0 {
+		writeTag(b, spanFlags, proto.WireFixed32)
+		b.EncodeFixed32(flags)
+	}
+
+	return b.Bytes()
+}
+
+func marshalKeyValue(attr Attribute) []byte {
+	b := proto.NewBuffer(nil)
+	writeString(b, keyValueKey, attr.Key)
+	writeMessage(b, keyValueValue, marshalAnyValue(attr.Value))
+	return b.Bytes()
+}
+
+func marshalAnyValue(v AttributeValue) []byte {
+	// Oneof fields are always written, even when they hold the zero value
+	b := proto.NewBuffer(nil)
+	switch {
+	case v.BoolValue != nil:
+		writeTag(b, anyValueBool, proto.WireVarint)
+		if *v.BoolValue {
+			b.EncodeVarint(1)
+		} else {
+			b.EncodeVarint(0)
+		}
+	case v.IntValue != nil:
+		writeTag(b, anyValueInt, proto.WireVarint)
+		b.EncodeVarint(uint64(*v.IntValue))
+	case v.DoubleValue != nil:
+		writeTag(b, anyValueDouble, proto.WireFixed64)
+		b.EncodeFixed64(math.Float64bits(*v.DoubleValue))
+	case v.ArrayValue != nil:
+		array := proto.NewBuffer(nil)
+		for _, value := range v.ArrayValue.Values {
+			writeMessage(array, arrayValueValues, marshalAnyValue(value))
+		}
+		writeMessage(b, anyValueArray, array.Bytes())
+	case v.KvlistValue != nil:
+		kvlist := proto.NewBuffer(nil)
+		for _, kv := range v.KvlistValue.Values {
+			writeMessage(kvlist, keyValueListValues, marshalKeyValue(kv))
+		}
+		writeMessage(b, anyValueKvlist, kvlist.Bytes())
+	case v.BytesValue != "":
+		raw, _ := base64.StdEncoding.DecodeString(v.BytesValue)
+		writeTag(b, anyValueBytes, proto.WireBytes)
+		b.EncodeRawBytes(raw)
+	default:
+		writeTag(b, anyValueString, proto.WireBytes)
+		b.EncodeStringBytes(v.StringValue)
+	}
+	return b.Bytes()
+}
+
+// parseUnixNano parses a decimal nanosecond timestamp, returning 0 if invalid
+func parseUnixNano(s string) uint64 {
+	n, _ := strconv.ParseUint(s, 10, 64)
+	return n
+}
+
+// The helpers below skip proto3 default values, matching generated code.
+// proto.Buffer writes to memory, so its encode methods never fail.
+
+func writeTag(b *proto.Buffer, field int, wireType int) {
+	b.EncodeVarint(uint64(field)<<3 | uint64(wireType))
+}
+
+func writeMessage(b *proto.Buffer, field int, msg []byte) {
+	writeTag(b, field, proto.WireBytes)
+	b.EncodeRawBytes(msg)
+}
+
+func writeString(b *proto.Buffer, field int, s string) {
+	if s == "" {
+		return
+	}
+	writeTag(b, field, proto.WireBytes)
+	b.EncodeStringBytes(s)
+}
+
+func writeHexBytes(b *proto.Buffer, field int, s string) {
+	raw, err := hex.DecodeString(s)
+	if err != nil || len(raw) == 0 {
+		return
+	}
+	writeTag(b, field, proto.WireBytes)
+	b.EncodeRawBytes(raw)
+}
+
+func writeVarint(b *proto.Buffer, field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	writeTag(b, field, proto.WireVarint)
+	b.EncodeVarint(v)
+}
+
+func writeFixed64(b *proto.Buffer, field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	writeTag(b, field, proto.WireFixed64)
+	b.EncodeFixed64(v)
+}
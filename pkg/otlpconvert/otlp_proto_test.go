@@ -0,0 +1,395 @@
+package otlpconvert
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+// wireField is one field of a protobuf message as read off the wire: a
+// varint, fixed64 or fixed32 in num, or length-delimited bytes in data
+type wireField struct {
+	field int
+	wire  int
+	num   uint64
+	data  []byte
+}
+
+// wireMessage is a decoded message, its fields in wire order
+type wireMessage []wireField
+
+// decodeWire reads data field by field, failing on any malformed field
+func decodeWire(t *testing.T, data []byte) wireMessage {
+	t.Helper()
+	var m wireMessage
+	varint := func(field int) uint64 {
+		v, n := binary.Uvarint(data)
+		if n <= 0 {
+			t.Fatalf("field %d: bad varint", field)
+		}
+		data = data[n:]
+		return v
+	}
+	take := func(field, n int) []byte {
+		if n < 0 || n > len(data) {
+			t.Fatalf("field %d: %d bytes past the end", field, n)
+		}
+		b := data[:n]
+		data = data[n:]
+		return b
+	}
+	for len(data) > 0 {
+		tag := varint(0)
+		f := wireField{field: int(tag >> 3), wire: int(tag & 7)}
+		switch f.wire {
+		case proto.WireVarint:
+			f.num = varint(f.field)
+		case proto.WireFixed64:
+			f.num = binary.LittleEndian.Uint64(take(f.field, 8))
+		case proto.WireFixed32:
+			f.num = uint64(binary.LittleEndian.Uint32(take(f.field, 4)))
+		case proto.WireBytes:
+			f.data = take(f.field, int(varint(f.field)))
+		default:
+			t.Fatalf("field %d: unexpected wire type %d", f.field, f.wire)
+		}
+		m = append(m, f)
+	}
+	return m
+}
+
+// all returns every occurrence of field
+func (m wireMessage) all(field int) []wireField {
+	var fields []wireField
+	for _, f := range m {
+		if f.field == field {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// one returns field, which must occur exactly once with the given wire type
+func (m wireMessage) one(t *testing.T, field, wire int) wireField {
+	t.Helper()
+	fields := m.all(field)
+	if len(fields) != 1 {
+		t.Fatalf("field %d occurs %d times, want 1", field, len(fields))
+	}
+	if fields[0].wire != wire {
+		t.Fatalf("field %d has wire type %d, want %d", field, fields[0].wire, wire)
+	}
+	return fields[0]
+}
+
+// msg decodes a length-delimited field as a message
+func (f wireField) msg(t *testing.T) wireMessage {
+	t.Helper()
+	return decodeWire(t, f.data)
+}
+
+// fieldSet lists the field numbers present in m, in order, without repeats
+func (m wireMessage) fieldSet() []int {
+	var set []int
+	for _, f := range m {
+		if len(set) == 0 || set[len(set)-1] != f.field {
+			set = append(set, f.field)
+		}
+	}
+	return set
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMarshalOTLPProto(t *testing.T) {
+	yes, no := true, false
+	negative := int64(-5)
+	pi := 3.25
+	nestedInt := int64(7)
+	values := []Attribute{
+		{Key: "string", Value: AttributeValue{StringValue: "v"}},
+		{Key: "empty string", Value: AttributeValue{}},
+		{Key: "true", Value: AttributeValue{BoolValue: &yes}},
+		{Key: "false", Value: AttributeValue{BoolValue: &no}},
+		{Key: "int", Value: AttributeValue{IntValue: &negative}},
+		{Key: "double", Value: AttributeValue{DoubleValue: &pi}},
+		{Key: "bytes", Value: AttributeValue{BytesValue: base64.StdEncoding.EncodeToString([]byte{0, 1, 2})}},
+		{Key: "array", Value: AttributeValue{ArrayValue: &ArrayValue{Values: []AttributeValue{
+			{StringValue: "a"},
+			{ArrayValue: &ArrayValue{Values: []AttributeValue{{IntValue: &nestedInt}}}},
+		}}}},
+		{Key: "kvlist", Value: AttributeValue{KvlistValue: &KeyValueList{Values: []Attribute{
+			{Key: "inner", Value: AttributeValue{BoolValue: &yes}},
+			{Key: "nested", Value: AttributeValue{KvlistValue: &KeyValueList{Values: []Attribute{
+				{Key: "deep", Value: AttributeValue{StringValue: "d"}},
+			}}}},
+		}}}},
+	}
+	span := &OTLPSpan{
+		TraceID:           "0102030405060708090a0b0c0d0e0f10",
+		SpanID:            "1112131415161718",
+		TraceState:        "k=v",
+		ParentSpanID:      "2122232425262728",
+		Name:              "op",
+		Kind:              "SPAN_KIND_CLIENT",
+		StartTimeUnixNano: "1700000000000000000",
+		EndTimeUnixNano:   "1700000000001000000",
+		Attributes:        values,
+		Events: []Event{{
+			TimeUnixNano:           "1700000000000500000",
+			Name:                   "event",
+			Attributes:             []Attribute{{Key: "e", Value: AttributeValue{StringValue: "1"}}},
+			DroppedAttributesCount: 1,
+		}},
+		Links: []Link{{
+			TraceID:    "3132333435363738393a3b3c3d3e3f40",
+			SpanID:     "4142434445464748",
+			Attributes: []Attribute{{Key: "l", Value: AttributeValue{StringValue: "2"}}},
+		}},
+		Status:                 Status{Code: "STATUS_CODE_ERROR", Message: "boom"},
+		TraceFlags:             "01",
+		DroppedAttributesCount: 2,
+		DroppedEventsCount:     3,
+		DroppedLinksCount:      4,
+	}
+	export := OTLPExport{ResourceSpans: []ResourceSpans{{
+		Resource: Resource{Attributes: []Attribute{{Key: "service.name", Value: AttributeValue{StringValue: "svc"}}}},
+		ScopeSpans: []ScopeSpans{{
+			Scope:     &InstrumentationScope{Name: "lib", Version: "1.0"},
+			Spans:     []*OTLPSpan{span},
+			SchemaURL: "https://example.com/scope",
+		}},
+		SchemaURL: "https://example.com/resource",
+	}}}
+
+	tracesData := decodeWire(t, MarshalOTLPProto(export))
+	rs := tracesData.one(t, tracesDataResourceSpans, proto.WireBytes).msg(t)
+	if got := string(rs.one(t, resourceSpansSchemaURL, proto.WireBytes).data); got != "https://example.com/resource" {
+		t.Errorf("resource schema_url = %q", got)
+	}
+	resource := rs.one(t, resourceSpansResource, proto.WireBytes).msg(t)
+	checkKeyValue(t, resource.one(t, resourceAttributes, proto.WireBytes).msg(t), "service.name", anyValueString, "svc")
+
+	ss := rs.one(t, resourceSpansScopeSpans, proto.WireBytes).msg(t)
+	scope := ss.one(t, scopeSpansScope, proto.WireBytes).msg(t)
+	if string(scope.one(t, scopeName, proto.WireBytes).data) != "lib" || string(scope.one(t, scopeVersion, proto.WireBytes).data) != "1.0" {
+		t.Errorf("scope = %+v", scope)
+	}
+	if got := string(ss.one(t, scopeSpansSchemaURL, proto.WireBytes).data); got != "https://example.com/scope" {
+		t.Errorf("scope schema_url = %q", got)
+	}
+
+	s := ss.one(t, scopeSpansSpans, proto.WireBytes).msg(t)
+	wantFields := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	if got := s.fieldSet(); !equalInts(got, wantFields) {
+		t.Errorf("span fields = %v, want %v", got, wantFields)
+	}
+	checkHex(t, "trace_id", s.one(t, spanTraceID, proto.WireBytes).data, span.TraceID)
+	checkHex(t, "span_id", s.one(t, spanSpanID, proto.WireBytes).data, span.SpanID)
+	checkHex(t, "parent_span_id", s.one(t, spanParentSpanID, proto.WireBytes).data, span.ParentSpanID)
+	if got := string(s.one(t, spanTraceState, proto.WireBytes).data); got != "k=v" {
+		t.Errorf("trace_state = %q", got)
+	}
+	if got := string(s.one(t, spanName, proto.WireBytes).data); got != "op" {
+		t.Errorf("name = %q", got)
+	}
+	scalars := []struct {
+		name  string
+		field int
+		wire  int
+		want  uint64
+	}{
+		{"kind", spanKind, proto.WireVarint, 3},
+		{"start_time_unix_nano", spanStartTime, proto.WireFixed64, 1700000000000000000},
+		{"end_time_unix_nano", spanEndTime, proto.WireFixed64, 1700000000001000000},
+		{"dropped_attributes_count", spanDroppedAttrs, proto.WireVarint, 2},
+		{"dropped_events_count", spanDroppedEvts, proto.WireVarint, 3},
+		{"dropped_links_count", spanDroppedLinks, proto.WireVarint, 4},
+		{"flags", spanFlags, proto.WireFixed32, 1},
+	}
+	for _, sc := range scalars {
+		if got := s.one(t, sc.field, sc.wire).num; got != sc.want {
+			t.Errorf("%s = %d, want %d", sc.name, got, sc.want)
+		}
+	}
+
+	status := s.one(t, spanStatus, proto.WireBytes).msg(t)
+	if got := string(status.one(t, statusMessage, proto.WireBytes).data); got != "boom" {
+		t.Errorf("status message = %q", got)
+	}
+	if got := status.one(t, statusCode, proto.WireVarint).num; got != 2 {
+		t.Errorf("status code = %d, want 2", got)
+	}
+
+	event := s.one(t, spanEvents, proto.WireBytes).msg(t)
+	if got := event.one(t, eventTime, proto.WireFixed64).num; got != 1700000000000500000 {
+		t.Errorf("event time = %d", got)
+	}
+	if got := string(event.one(t, eventName, proto.WireBytes).data); got != "event" {
+		t.Errorf("event name = %q", got)
+	}
+	checkKeyValue(t, event.one(t, eventAttributes, proto.WireBytes).msg(t), "e", anyValueString, "1")
+	if got := event.one(t, eventDroppedAttrs, proto.WireVarint).num; got != 1 {
+		t.Errorf("event dropped_attributes_count = %d, want 1", got)
+	}
+
+	link := s.one(t, spanLinks, proto.WireBytes).msg(t)
+	checkHex(t, "link trace_id", link.one(t, linkTraceID, proto.WireBytes).data, span.Links[0].TraceID)
+	checkHex(t, "link span_id", link.one(t, linkSpanID, proto.WireBytes).data, span.Links[0].SpanID)
+	checkKeyValue(t, link.one(t, linkAttributes, proto.WireBytes).msg(t), "l", anyValueString, "2")
+
+	// Every AnyValue variant, in attribute order
+	attrs := s.all(spanAttributes)
+	if len(attrs) != len(values) {
+		t.Fatalf("%d attributes, want %d", len(attrs), len(values))
+	}
+	kv := func(i int) wireMessage { return attrs[i].msg(t) }
+	checkKeyValue(t, kv(0), "string", anyValueString, "v")
+	checkKeyValue(t, kv(1), "empty string", anyValueString, "")
+	checkKeyValue(t, kv(2), "true", anyValueBool, uint64(1))
+	checkKeyValue(t, kv(3), "false", anyValueBool, uint64(0))
+	checkKeyValue(t, kv(4), "int", anyValueInt, uint64(math.MaxUint64-4)) // two's complement of -5
+	checkKeyValue(t, kv(5), "double", anyValueDouble, math.Float64bits(pi))
+	checkKeyValue(t, kv(6), "bytes", anyValueBytes, "\x00\x01\x02")
+
+	array := anyValue(t, kv(7), "array").one(t, anyValueArray, proto.WireBytes).msg(t)
+	elements := array.all(arrayValueValues)
+	if len(elements) != 2 {
+		t.Fatalf("array has %d values, want 2", len(elements))
+	}
+	checkAnyValue(t, "array[0]", elements[0].msg(t), anyValueString, "a")
+	inner := elements[1].msg(t).one(t, anyValueArray, proto.WireBytes).msg(t)
+	checkAnyValue(t, "array[1][0]", inner.one(t, arrayValueValues, proto.WireBytes).msg(t), anyValueInt, uint64(7))
+
+	kvlist := anyValue(t, kv(8), "kvlist").one(t, anyValueKvlist, proto.WireBytes).msg(t)
+	entries := kvlist.all(keyValueListValues)
+	if len(entries) != 2 {
+		t.Fatalf("kvlist has %d values, want 2", len(entries))
+	}
+	checkKeyValue(t, entries[0].msg(t), "inner", anyValueBool, uint64(1))
+	nested := anyValue(t, entries[1].msg(t), "nested").one(t, anyValueKvlist, proto.WireBytes).msg(t)
+	checkKeyValue(t, nested.one(t, keyValueListValues, proto.WireBytes).msg(t), "deep", anyValueString, "d")
+}
+
+// anyValue checks the key of a KeyValue message and returns its AnyValue
+func anyValue(t *testing.T, kv wireMessage, key string) wireMessage {
+	t.Helper()
+	if got := string(kv.one(t, keyValueKey, proto.WireBytes).data); got != key {
+		t.Errorf("key = %q, want %q", got, key)
+	}
+	return kv.one(t, keyValueValue, proto.WireBytes).msg(t)
+}
+
+// checkKeyValue checks a KeyValue message holds key and an AnyValue with
+// only field set to want (a string for length-delimited fields)
+func checkKeyValue(t *testing.T, kv wireMessage, key string, field int, want any) {
+	t.Helper()
+	checkAnyValue(t, key, anyValue(t, kv, key), field, want)
+}
+
+// checkAnyValue checks an AnyValue message has exactly one field, field,
+// holding want
+func checkAnyValue(t *testing.T, name string, v wireMessage, field int, want any) {
+	t.Helper()
+	if len(v) != 1 || v[0].field != field {
+		t.Errorf("%s: value fields %v, want only %d", name, v.fieldSet(), field)
+		return
+	}
+	switch want := want.(type) {
+	case string:
+		if v[0].wire != proto.WireBytes || string(v[0].data) != want {
+			t.Errorf("%s: value = %q, want %q", name, v[0].data, want)
+		}
+	case uint64:
+		if v[0].wire == proto.WireBytes || v[0].num != want {
+			t.Errorf("%s: value = %d, want %d", name, v[0].num, want)
+		}
+	}
+}
+
+// checkHex checks raw bytes against a hex ID
+func checkHex(t *testing.T, name string, got []byte, want string) {
+	t.Helper()
+	raw, _ := hex.DecodeString(want)
+	if !bytes.Equal(got, raw) {
+		t.Errorf("%s = %x, want %s", name, got, want)
+	}
+}
+
+// TestOTLPFieldNumbers checks the encoder's field numbers against
+// opentelemetry/proto/trace/v1/trace.proto and common/v1/common.proto, since
+// TestMarshalOTLPProto reads the output back with the same constants
+func TestOTLPFieldNumbers(t *testing.T) {
+	tests := []struct {
+		field string
+		got   int
+		want  int
+	}{
+		{"TracesData.resource_spans", tracesDataResourceSpans, 1},
+		{"ResourceSpans.resource", resourceSpansResource, 1},
+		{"ResourceSpans.scope_spans", resourceSpansScopeSpans, 2},
+		{"ResourceSpans.schema_url", resourceSpansSchemaURL, 3},
+		{"Resource.attributes", resourceAttributes, 1},
+		{"ScopeSpans.scope", scopeSpansScope, 1},
+		{"ScopeSpans.spans", scopeSpansSpans, 2},
+		{"ScopeSpans.schema_url", scopeSpansSchemaURL, 3},
+		{"InstrumentationScope.name", scopeName, 1},
+		{"InstrumentationScope.version", scopeVersion, 2},
+		{"Span.trace_id", spanTraceID, 1},
+		{"Span.span_id", spanSpanID, 2},
+		{"Span.trace_state", spanTraceState, 3},
+		{"Span.parent_span_id", spanParentSpanID, 4},
+		{"Span.name", spanName, 5},
+		{"Span.kind", spanKind, 6},
+		{"Span.start_time_unix_nano", spanStartTime, 7},
+		{"Span.end_time_unix_nano", spanEndTime, 8},
+		{"Span.attributes", spanAttributes, 9},
+		{"Span.dropped_attributes_count", spanDroppedAttrs, 10},
+		{"Span.events", spanEvents, 11},
+		{"Span.dropped_events_count", spanDroppedEvts, 12},
+		{"Span.links", spanLinks, 13},
+		{"Span.dropped_links_count", spanDroppedLinks, 14},
+		{"Span.status", spanStatus, 15},
+		{"Span.flags", spanFlags, 16},
+		{"Span.Event.time_unix_nano", eventTime, 1},
+		{"Span.Event.name", eventName, 2},
+		{"Span.Event.attributes", eventAttributes, 3},
+		{"Span.Event.dropped_attributes_count", eventDroppedAttrs, 4},
+		{"Span.Link.trace_id", linkTraceID, 1},
+		{"Span.Link.span_id", linkSpanID, 2},
+		{"Span.Link.attributes", linkAttributes, 4},
+		{"Status.message", statusMessage, 2},
+		{"Status.code", statusCode, 3},
+		{"KeyValue.key", keyValueKey, 1},
+		{"KeyValue.value", keyValueValue, 2},
+		{"AnyValue.string_value", anyValueString, 1},
+		{"AnyValue.bool_value", anyValueBool, 2},
+		{"AnyValue.int_value", anyValueInt, 3},
+		{"AnyValue.double_value", anyValueDouble, 4},
+		{"AnyValue.array_value", anyValueArray, 5},
+		{"AnyValue.kvlist_value", anyValueKvlist, 6},
+		{"AnyValue.bytes_value", anyValueBytes, 7},
+		{"ArrayValue.values", arrayValueValues, 1},
+		{"KeyValueList.values", keyValueListValues, 1},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %d, want %d", tt.field, tt.got, tt.want)
+		}
+	}
+}
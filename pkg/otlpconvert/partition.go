@@ -0,0 +1,108 @@
+package otlpconvert
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// outputPartition is a subset of a batch written under its own file prefix
+type outputPartition struct {
+	prefix string
+	traces map[string][]*OTLPSpan
+}
+
+// timeWindowLayouts maps time partitions to the layout of their window key
+var timeWindowLayouts = map[string]string{
+	"minute": "200601021504",
+	"hour":   "2006010215",
+	"day":    "20060102",
+}
+
+// timeWindow returns the UTC time window a span falls into when partitioning
+// by time, or "" otherwise
+func (c *Converter) timeWindow(span *OTLPSpan) string {
+	layout, ok := timeWindowLayouts[c.config.PartitionBy]
+	if !ok {
+		return ""
+	}
+
+	nanos, _ := strconv.ParseInt(span.StartTimeUnixNano, 10, 64)
+	return time.Unix(0, nanos).UTC().Format(layout)
+}
+
+// partition splits a batch according to Config.PartitionBy. Without
+// partitioning the whole batch is written under the output base name.
+func (c *Converter) partition(batch writeBatch) []outputPartition {
+	traces := batch.traces
+
+	if batch.window != "" {
+		// Time partitions are cut by the collector, one window per batch
+		return []outputPartition{{prefix: c.config.OutputFile + "." + batch.window, traces: traces}}
+	}
+
+	switch c.config.PartitionBy {
+	case "service":
+		groups := groupByService(traces)
+
+		names := make([]string, 0, len(groups))
+		for name := range groups {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		parts := make([]outputPartition, 0, len(groups))
+		for _, name := range names {
+			parts = append(parts, outputPartition{
+				prefix: c.config.OutputFile + "." + name,
+				traces: groups[name],
+			})
+		}
+		return parts
+	default:
+		return []outputPartition{{prefix: c.config.OutputFile, traces: traces}}
+	}
+}
+
+// groupByService splits traces by the service.name of each span, keyed by
+// the sanitized name used in file names. A trace that crosses services appears
+// in each of their groups with only that service's spans. Services whose names
+// sanitize alike (e.g. "checkout/api" and "checkout api") share a group, as
+// separate groups would write the same file and the later would replace the
+// earlier.
+func groupByService(traces map[string][]*OTLPSpan) map[string]map[string][]*OTLPSpan {
+	groups := make(map[string]map[string][]*OTLPSpan)
+	for traceID, spans := range traces {
+		for _, span := range spans {
+			service := sanitizeFilename(spanServiceName(span))
+			group, ok := groups[service]
+			if !ok {
+				group = make(map[string][]*OTLPSpan)
+				groups[service] = group
+			}
+			group[traceID] = append(group[traceID], span)
+		}
+	}
+	return groups
+}
+
+// sanitizeFilename makes a value safe to embed in a file name by replacing
+// path separators, whitespace and other unusual characters with underscores
+func sanitizeFilename(s string) string {
+	sanitized := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+
+	// Avoid hidden files and relative path components such as ".."
+	sanitized = strings.Trim(sanitized, ".")
+	if sanitized == "" {
+		return "unknown"
+	}
+	return sanitized
+}
@@ -0,0 +1,70 @@
+package otlpconvert
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	jaeger "github.com/jaegertracing/jaeger/model"
+)
+
+// TestPartitionSanitizedCollision checks services whose names sanitize to
+// the same file name share that file instead of overwriting each other
+func TestPartitionSanitizedCollision(t *testing.T) {
+	dir := t.TempDir()
+	c := New(Config{
+		OutputFile:   filepath.Join(dir, "out"),
+		OutputFormat: "json",
+		PartitionBy:  "service",
+	})
+
+	services := []string{"checkout/api", "checkout api", "checkout_api", "other"}
+	traces := make(map[string][]*OTLPSpan)
+	for i, service := range services {
+		span := testSpan(uint64(i + 1))
+		span.Process = &jaeger.Process{ServiceName: service}
+		otlp := c.ConvertJaegerSpan(span)
+		traces[otlp.TraceID] = append(traces[otlp.TraceID], otlp)
+	}
+	c.writeOutput(writeBatch{traces: traces})
+
+	if got := c.LostBatches(); got != 0 {
+		t.Errorf("lost batches = %d, want 0", got)
+	}
+	if got := c.TotalSpans(); got != len(services) {
+		t.Errorf("spans written = %d, want %d", got, len(services))
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []string
+	for _, entry := range entries {
+		files = append(files, entry.Name())
+	}
+	want := []string{"out.checkout_api.batch_0000.otlp.json", "out.other.batch_0000.otlp.json"}
+	if len(files) != len(want) || files[0] != want[0] || files[1] != want[1] {
+		t.Fatalf("files = %v, want %v", files, want)
+	}
+
+	export, err := readOTLPJSONFile(filepath.Join(dir, want[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, rs := range export.ResourceSpans {
+		service, _ := attr(rs.Resource.Attributes, "service.name")
+		for _, ss := range rs.ScopeSpans {
+			for range ss.Spans {
+				got = append(got, service.StringValue)
+			}
+		}
+	}
+	sort.Strings(got)
+	wantServices := []string{"checkout api", "checkout/api", "checkout_api"}
+	if len(got) != len(wantServices) || got[0] != wantServices[0] || got[1] != wantServices[1] || got[2] != wantServices[2] {
+		t.Errorf("services in %s = %v, want %v", want[0], got, wantServices)
+	}
+}
@@ -0,0 +1,72 @@
+package otlpconvert
+
+import "sync"
+
+// spanPool recycles OTLPSpans, and the backing arrays of their slices, between
+// conversions. On large runs allocating a fresh span per entry is the main
+// source of GC pressure.
+//
+// Ownership: a span is taken from the pool by convertJaegerToOTLP, owned by
+// the collector while buffered, and handed to the writer with its batch. Only
+// writeOutput (after every output for the batch is written) and the
+// collector (for spans it drops) return spans to the pool. Spans handed to
+// library callers, through ConvertJaegerSpan or a Worker result channel they
+// drain themselves, are reclaimed only if the caller passes them to
+// ReleaseSpan.
+var spanPool = sync.Pool{
+	New: func() any { return new(OTLPSpan) },
+}
+
+// getSpan returns a zeroed span whose Attributes, Events and Links are empty
+// but non-nil, reusing pooled capacity where available
+func getSpan() *OTLPSpan {
+	span := spanPool.Get().(*OTLPSpan)
+	if span.Attributes == nil {
+		span.Attributes = make([]Attribute, 0)
+	}
+	if span.Events == nil {
+		span.Events = make([]Event, 0)
+	}
+	if span.Links == nil {
+		span.Links = make([]Link, 0)
+	}
+	return span
+}
+
+// releaseSpan resets a span and returns it to the pool. The caller must not
+// use the span afterwards.
+func releaseSpan(span *OTLPSpan) {
+	// Clear the old elements so pooled arrays do not pin their strings
+	clear(span.Attributes)
+	clear(span.Events)
+	clear(span.Links)
+	if span.sharedResource {
+		span.Resource = nil
+	} else {
+		clear(span.Resource)
+	}
+	*span = OTLPSpan{
+		Attributes: span.Attributes[:0],
+		Events:     span.Events[:0],
+		Links:      span.Links[:0],
+		Resource:   span.Resource[:0],
+	}
+	spanPool.Put(span)
+}
+
+// ReleaseSpan returns a span the caller is done with to the pool, so the
+// next conversion can reuse its memory. The span and its slices must not be
+// used afterwards. Releasing is optional; unreleased spans are simply
+// garbage collected.
+func ReleaseSpan(span *OTLPSpan) {
+	releaseSpan(span)
+}
+
+// releaseTraces returns every span in traces to the pool
+func releaseTraces(traces map[string][]*OTLPSpan) {
+	for _, spans := range traces {
+		for _, span := range spans {
+			releaseSpan(span)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package otlpconvert
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	jaeger "github.com/jaegertracing/jaeger/model"
+)
+
+// benchmarkSpan returns a span shaped like typical instrumented traffic: a
+// parent, ten tags, three logs and a process with tags
+func benchmarkSpan(id uint64) *jaeger.Span {
+	span := &jaeger.Span{
+		TraceID:       jaeger.NewTraceID(1, id),
+		SpanID:        jaeger.NewSpanID(id),
+		OperationName: "GET /api/users",
+		StartTime:     time.Unix(1700000000, 0),
+		Duration:      time.Millisecond,
+		References:    []jaeger.SpanRef{jaeger.NewChildOfRef(jaeger.NewTraceID(1, id), jaeger.SpanID(id+1))},
+		Process: &jaeger.Process{
+			ServiceName: "svc",
+			Tags:        []jaeger.KeyValue{jaeger.String("host", "h"), jaeger.String("ip", "10.0.0.1")},
+		},
+	}
+	for i := 0; i < 10; i++ {
+		span.Tags = append(span.Tags, jaeger.String(fmt.Sprintf("tag%d", i), "value"))
+	}
+	for i := 0; i < 3; i++ {
+		span.Logs = append(span.Logs, jaeger.Log{
+			Timestamp: time.Unix(1700000000, int64(i)),
+			Fields:    []jaeger.KeyValue{jaeger.String("event", "e"), jaeger.Int64("n", int64(i))},
+		})
+	}
+	return span
+}
+
+// BenchmarkConvertJaegerSpan compares converting with spans returned to the
+// pool, as the pipeline does once a batch is written, against keeping every
+// span, which allocates each one afresh as before pooling
+func BenchmarkConvertJaegerSpan(b *testing.B) {
+	span := benchmarkSpan(1)
+	b.Run("released", func(b *testing.B) {
+		c := New(Config{})
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			releaseSpan(c.ConvertJaegerSpan(span))
+		}
+	})
+	b.Run("retained", func(b *testing.B) {
+		c := New(Config{})
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			c.ConvertJaegerSpan(span)
+		}
+	})
+}
+
+func TestReleaseSpanReuse(t *testing.T) {
+	c := New(Config{})
+	span := c.ConvertJaegerSpan(benchmarkSpan(1))
+	releaseSpan(span)
+
+	// A recycled span must come back fully reset
+	reused := getSpan()
+	if reused.TraceID != "" || reused.Name != "" || len(reused.Attributes) != 0 || len(reused.Events) != 0 ||
+		len(reused.Links) != 0 || len(reused.Resource) != 0 || reused.DroppedEventsCount != 0 {
+		t.Errorf("pooled span not reset: %+v", reused)
+	}
+	again := c.ConvertJaegerSpan(benchmarkSpan(2))
+	if again.TraceID != "00000000000000010000000000000002" || len(again.Attributes) != 10 || len(again.Events) != 3 {
+		t.Errorf("span converted into recycled memory is wrong: %+v", again)
+	}
+}
@@ -0,0 +1,61 @@
+package otlpconvert
+
+import (
+	"sync"
+
+	jaeger "github.com/jaegertracing/jaeger/model"
+)
+
+// processCache interns the resource attributes built from Jaeger processes.
+// Most spans in an export repeat one of a handful of processes, so converting
+// each distinct process once and sharing the result saves both the tag
+// conversion and a resource slice per buffered span.
+type processCache struct {
+	mu        sync.RWMutex
+	resources map[string][]Attribute // marshaled jaeger.Process -> resource
+}
+
+func (p *processCache) len() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.resources)
+}
+
+// cachedResource returns the shared resource attributes for the span's
+// process, building and caching them on first sight. It reports false when
+// DedupProcesses is off or the resource cannot be shared: without a process,
+// or when service.name falls back to ServiceFromTag, which may read the
+// span's own tags. The returned slice must not be modified.
+func (c *Converter) cachedResource(jaegerSpan *jaeger.Span) ([]Attribute, bool) {
+	process := jaegerSpan.Process
+	if c.processes == nil || process == nil {
+		return nil, false
+	}
+	if process.ServiceName == "" && c.config.ServiceFromTag != "" {
+		return nil, false
+	}
+
+	// Identical processes marshal to identical bytes, tag order included
+	data, err := process.Marshal()
+	if err != nil {
+		return nil, false
+	}
+	key := string(data)
+
+	c.processes.mu.RLock()
+	resource, ok := c.processes.resources[key]
+	c.processes.mu.RUnlock()
+	if ok {
+		return resource, true
+	}
+
+	resource = c.buildResource(nil, jaegerSpan)
+	c.processes.mu.Lock()
+	defer c.processes.mu.Unlock()
+	// Another worker may have cached the same process meanwhile; keep one copy
+	if existing, ok := c.processes.resources[key]; ok {
+		return existing, true
+	}
+	c.processes.resources[key] = resource
+	return resource, true
+}
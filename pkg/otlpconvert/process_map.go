@@ -0,0 +1,141 @@
+package otlpconvert
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	jaeger "github.com/jaegertracing/jaeger/model"
+)
+
+// ProcessMap resolves the ProcessID of spans stored without an embedded
+// Process, for Config.ProcessMap
+type ProcessMap map[string]*jaeger.Process
+
+// processMapTag is a process tag in the Jaeger query API JSON
+type processMapTag struct {
+	Key   string          `json:"key"`
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// LoadProcessMap reads a JSON object mapping process IDs to processes in the
+// form of the "processes" object of a Jaeger query API trace, e.g.
+// {"p1": {"serviceName": "frontend", "tags": [{"key": "hostname",
+// "type": "string", "value": "web-1"}]}}. Tag types are string, bool, int64,
+// float64 and binary (base64).
+func LoadProcessMap(filename string) (ProcessMap, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read process map: %w", err)
+	}
+
+	var raw map[string]struct {
+		ServiceName string          `json:"serviceName"`
+		Tags        []processMapTag `json:"tags"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse process map: %w", err)
+	}
+
+	processes := make(ProcessMap, len(raw))
+	for id, p := range raw {
+		if id == "" {
+			return nil, fmt.Errorf("process map: empty process ID")
+		}
+		process := &jaeger.Process{ServiceName: p.ServiceName}
+		for _, tag := range p.Tags {
+			kv, err := tag.keyValue()
+			if err != nil {
+				return nil, fmt.Errorf("process map: process %q: %w", id, err)
+			}
+			process.Tags = append(process.Tags, kv)
+		}
+		processes[id] = process
+	}
+
+	return processes, nil
+}
+
+// keyValue converts a process map tag to a Jaeger tag
+func (t processMapTag) keyValue() (jaeger.KeyValue, error) {
+	if t.Key == "" {
+		return jaeger.KeyValue{}, fmt.Errorf("tag with empty key")
+	}
+
+	var err error
+	switch t.Type {
+	case "", "string":
+		var v string
+		if err = json.Unmarshal(t.Value, &v); err == nil {
+			return jaeger.String(t.Key, v), nil
+		}
+	case "bool":
+		var v bool
+		if err = json.Unmarshal(t.Value, &v); err == nil {
+			return jaeger.Bool(t.Key, v), nil
+		}
+	case "int64":
+		// Decode via json.Number so large IDs keep every digit
+		var v json.Number
+		decoder := json.NewDecoder(bytes.NewReader(t.Value))
+		decoder.UseNumber()
+		if err = decoder.Decode(&v); err == nil {
+			var n int64
+			if n, err = v.Int64(); err == nil {
+				return jaeger.Int64(t.Key, n), nil
+			}
+		}
+	case "float64":
+		var v float64
+		if err = json.Unmarshal(t.Value, &v); err == nil {
+			return jaeger.Float64(t.Key, v), nil
+		}
+	case "binary":
+		var v string
+		if err = json.Unmarshal(t.Value, &v); err == nil {
+			var b []byte
+			if b, err = base64.StdEncoding.DecodeString(v); err == nil {
+				return jaeger.Binary(t.Key, b), nil
+			}
+		}
+	default:
+		return jaeger.KeyValue{}, fmt.Errorf("tag %q: unknown type %q", t.Key, t.Type)
+	}
+	return jaeger.KeyValue{}, fmt.Errorf("tag %q: invalid %s value: %w", t.Key, t.Type, err)
+}
+
+// resolveProcess fills in the process of a span stored with only a ProcessID
+// from Config.ProcessMap. Spans of the same process share one *jaeger.Process,
+// which must be treated as read-only. IDs missing from the map are counted
+// and the span is left as is.
+func (c *Converter) resolveProcess(span *jaeger.Span) {
+	if span.Process != nil || span.ProcessID == "" || c.config.ProcessMap == nil {
+		return
+	}
+	if process, ok := c.config.ProcessMap[span.ProcessID]; ok {
+		span.Process = process
+		return
+	}
+	c.unresolvedProcesses.Add(1)
+}
+
+// withProcess is resolveProcess for spans passed in by library callers: it
+// returns span itself, or a shallow copy with the process filled in, so the
+// caller's span is not modified
+func (c *Converter) withProcess(span *jaeger.Span) *jaeger.Span {
+	if span.Process != nil || span.ProcessID == "" || c.config.ProcessMap == nil {
+		return span
+	}
+	resolved := *span
+	c.resolveProcess(&resolved)
+	return &resolved
+}
+
+// UnresolvedProcesses returns how many spans had a ProcessID missing from
+// Config.ProcessMap
+func (c *Converter) UnresolvedProcesses() int64 {
+	return c.unresolvedProcesses.Load()
+}
@@ -0,0 +1,70 @@
+package otlpconvert
+
+import (
+	"encoding/json"
+	"testing"
+
+	jaeger "github.com/jaegertracing/jaeger/model"
+)
+
+// TestProcessMapEntryPoints checks every conversion entry point resolves a
+// span stored with only a ProcessID, and counts unresolved IDs once
+func TestProcessMapEntryPoints(t *testing.T) {
+	processMap := ProcessMap{"p1": &jaeger.Process{ServiceName: "frontend"}}
+	convert := map[string]func(c *Converter, span *jaeger.Span) string{
+		"ConvertJaegerSpan": func(c *Converter, span *jaeger.Span) string {
+			return spanServiceName(c.ConvertJaegerSpan(span))
+		},
+		"ConvertToOTLPJSON": func(c *Converter, span *jaeger.Span) string {
+			data, err := c.ConvertToOTLPJSON([]*jaeger.Span{span})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var export struct {
+				ResourceSpans []struct {
+					Resource struct {
+						Attributes []Attribute `json:"attributes"`
+					} `json:"resource"`
+				} `json:"resourceSpans"`
+			}
+			if err := json.Unmarshal(data, &export); err != nil {
+				t.Fatal(err)
+			}
+			value, _ := attr(export.ResourceSpans[0].Resource.Attributes, "service.name")
+			return value.StringValue
+		},
+		"ConvertEntry": func(c *Converter, span *jaeger.Span) string {
+			c.config.ValueEncoding, c.config.ValueShape = "raw", "span"
+			spans := c.ConvertEntry(marshalEntry(t, span))
+			return spanServiceName(spans[0])
+		},
+	}
+	tests := []struct {
+		processID      string
+		wantService    string
+		wantUnresolved int64
+	}{
+		{processID: "p1", wantService: "frontend"},
+		{processID: "p9", wantService: "unknown", wantUnresolved: 1},
+	}
+	for name, fn := range convert {
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.processID, func(t *testing.T) {
+				c := New(Config{ProcessMap: processMap})
+				span := testSpan(1)
+				span.Process = nil
+				span.ProcessID = tt.processID
+
+				if got := fn(c, span); got != tt.wantService {
+					t.Errorf("service.name = %q, want %q", got, tt.wantService)
+				}
+				if got := c.UnresolvedProcesses(); got != tt.wantUnresolved {
+					t.Errorf("unresolved processes = %d, want %d", got, tt.wantUnresolved)
+				}
+				if name != "ConvertEntry" && span.Process != nil {
+					t.Error("caller's span was modified")
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package otlpconvert
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket for Config.RateLimit, shared by all writers.
+// It refills at rate spans per second and holds at most one second's worth,
+// so an idle exporter can burst by at most that much. It starts empty, and a
+// batch larger than the bucket waits for the tokens it is short of, which
+// keeps the average at the limit from the first request whatever the batch
+// size.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // spans per second
+	tokens float64
+	last   time.Time // last refill
+
+	// Export window for effectiveRate; guarded by mu
+	first  time.Time // first wait
+	done   time.Time // last completed export
+	spans  int64     // spans exported
+	waited time.Duration
+}
+
+func newRateLimiter(rate int) *rateLimiter {
+	return &rateLimiter{rate: float64(rate)}
+}
+
+// wait blocks until n spans may be sent
+func (l *rateLimiter) wait(n int) {
+	l.mu.Lock()
+	now := time.Now()
+	if l.first.IsZero() {
+		l.first = now
+		l.last = now
+	}
+	l.tokens = min(l.tokens+now.Sub(l.last).Seconds()*l.rate, l.rate)
+	l.last = now
+	l.tokens -= float64(n)
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.waited += delay
+	l.mu.Unlock()
+
+	time.Sleep(delay)
+}
+
+// exported records n spans sent successfully
+func (l *rateLimiter) exported(n int) {
+	l.mu.Lock()
+	l.spans += int64(n)
+	l.done = time.Now()
+	l.mu.Unlock()
+}
+
+// effectiveRate returns the average spans per second between the first export
+// and the last successful one, and the total time writers were held back
+func (l *rateLimiter) effectiveRate() (float64, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	elapsed := l.done.Sub(l.first).Seconds()
+	if l.spans == 0 || elapsed <= 0 {
+		return 0, l.waited
+	}
+	return float64(l.spans) / elapsed, l.waited
+}
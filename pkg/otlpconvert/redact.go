@@ -0,0 +1,54 @@
+package otlpconvert
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	jaeger "github.com/jaegertracing/jaeger/model"
+)
+
+// redactedValue replaces the value of attributes listed in Config.RedactKeys
+const redactedValue = "[REDACTED]"
+
+// keySet builds a lookup set from a list of keys
+func keySet(keys []string) map[string]bool {
+	if len(keys) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		set[key] = true
+	}
+	return set
+}
+
+// scrubValue applies RedactKeys and HashKeys to a converted tag. Keys match
+// either the Jaeger tag key or the attribute key it was renamed to. Hashed
+// values are the SHA-256 hex of the original value, so they stay joinable
+// across spans without being readable.
+func (c *Converter) scrubValue(tag jaeger.KeyValue, attr *Attribute) {
+	switch {
+	case c.redactKeys[tag.Key] || c.redactKeys[attr.Key]:
+		attr.Value = AttributeValue{StringValue: redactedValue}
+	case c.hashKeys[tag.Key] || c.hashKeys[attr.Key]:
+		var sum [sha256.Size]byte
+		if tag.VType == jaeger.ValueType_BINARY {
+			sum = sha256.Sum256(tag.VBinary)
+		} else {
+			sum = sha256.Sum256([]byte(tag.AsString()))
+		}
+		attr.Value = AttributeValue{StringValue: hex.EncodeToString(sum[:])}
+	}
+}
+
+// scrubbedString returns the value of tag as a string after RedactKeys and
+// HashKeys, for a tag value copied somewhere other than its attribute, like
+// the service name taken from ServiceFromTag
+func (c *Converter) scrubbedString(tag jaeger.KeyValue) string {
+	attr := Attribute{
+		Key:   c.attributeKey(tag.Key),
+		Value: AttributeValue{StringValue: tag.AsString()},
+	}
+	c.scrubValue(tag, &attr)
+	return attr.Value.StringValue
+}
@@ -0,0 +1,56 @@
+package otlpconvert
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// LoadRenameMap reads a JSON object mapping source tag keys to OTLP
+// attribute keys, e.g. {"http.status": "http.status_code"}
+func LoadRenameMap(filename string) (map[string]string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rename map: %w", err)
+	}
+
+	var renames map[string]string
+	if err := json.Unmarshal(data, &renames); err != nil {
+		return nil, fmt.Errorf("failed to parse rename map: %w", err)
+	}
+	for from, to := range renames {
+		if from == "" || to == "" {
+			return nil, fmt.Errorf("rename map: empty key in %q -> %q", from, to)
+		}
+	}
+
+	return renames, nil
+}
+
+// attributeKey returns the OTLP attribute key for a Jaeger tag key, applying
+// Config.RenameMap. Unmapped keys pass through unchanged apart from
+// Config.NormalizeKeys.
+func (c *Converter) attributeKey(key string) string {
+	if renamed, ok := c.config.RenameMap[key]; ok {
+		return renamed
+	}
+	return normalizeKey(key, c.config.NormalizeKeys)
+}
+
+// normalizeKey rewrites key for a Config.NormalizeKeys mode. "dots"
+// turns underscores into dots, but only in keys without a dot: a dotted key
+// such as http.status_code already follows the semantic conventions, whose
+// names may contain underscores. "underscores" turns every dot into an
+// underscore.
+func normalizeKey(key, mode string) string {
+	switch mode {
+	case "dots":
+		if !strings.Contains(key, ".") {
+			return strings.ReplaceAll(key, "_", ".")
+		}
+	case "underscores":
+		return strings.ReplaceAll(key, ".", "_")
+	}
+	return key
+}
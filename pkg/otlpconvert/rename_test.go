@@ -0,0 +1,76 @@
+package otlpconvert
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	jaeger "github.com/jaegertracing/jaeger/model"
+)
+
+func TestNormalizeKeys(t *testing.T) {
+	tags := []jaeger.KeyValue{
+		jaeger.String("http_method", "GET"),
+		jaeger.String("http.status_code", "200"),
+		jaeger.String("db.system", "pg"),
+		jaeger.String("plain", "x"),
+	}
+	tests := []struct {
+		name      string
+		mode      string
+		renameMap map[string]string
+		want      string // span attribute keys in order
+		wantEvent string // key of the error_kind log field
+	}{
+		{name: "none", mode: "", want: "http_method,http.status_code,db.system,plain", wantEvent: "error_kind"},
+		{name: "none explicit", mode: "none", want: "http_method,http.status_code,db.system,plain", wantEvent: "error_kind"},
+		{name: "underscores to dots", mode: "dots", want: "http.method,http.status_code,db.system,plain", wantEvent: "error.kind"},
+		{name: "dots to underscores", mode: "underscores", want: "http_method,http_status_code,db_system,plain", wantEvent: "error_kind"},
+		{
+			name:      "rename map wins over normalization",
+			mode:      "underscores",
+			renameMap: map[string]string{"db.system": "db.system"},
+			want:      "http_method,http_status_code,db.system,plain",
+			wantEvent: "error_kind",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			span := testSpan(1)
+			span.Tags = tags
+			span.Logs = []jaeger.Log{{
+				Timestamp: time.Unix(1700000000, 0),
+				Fields:    []jaeger.KeyValue{jaeger.String("error_kind", "x")},
+			}}
+			otlp := New(Config{NormalizeKeys: tt.mode, RenameMap: tt.renameMap}).ConvertJaegerSpan(span)
+
+			var keys []string
+			for _, a := range otlp.Attributes {
+				keys = append(keys, a.Key)
+			}
+			if got := strings.Join(keys, ","); got != tt.want {
+				t.Errorf("attribute keys = %s, want %s", got, tt.want)
+			}
+			if got := otlp.Events[0].Attributes[0].Key; got != tt.wantEvent {
+				t.Errorf("event attribute key = %s, want %s", got, tt.wantEvent)
+			}
+		})
+	}
+}
+
+// TestNormalizeKeyRoundTrip checks the two modes undo each other for keys
+// without both separators
+func TestNormalizeKeyRoundTrip(t *testing.T) {
+	for _, key := range []string{"a_b_c", "component", "peer_service"} {
+		dotted := normalizeKey(key, "dots")
+		if back := normalizeKey(dotted, "underscores"); back != key {
+			t.Errorf("%q -> %q -> %q, want %q back", key, dotted, back, key)
+		}
+	}
+	for _, key := range []string{"a.b.c", "http.method"} {
+		underscored := normalizeKey(key, "underscores")
+		if back := normalizeKey(underscored, "dots"); back != key {
+			t.Errorf("%q -> %q -> %q, want %q back", key, underscored, back, key)
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package otlpconvert
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	jaeger "github.com/jaegertracing/jaeger/model"
+)
+
+// processTagKeys maps well-known Jaeger process tags to OTLP resource
+// semantic convention keys
+var processTagKeys = map[string]string{
+	"hostname":    "host.name",
+	"ip":          "host.ip",
+	"client-uuid": "service.instance.id",
+}
+
+// convertProcessTag translates a Jaeger process tag into OTLP resource
+// attributes. It returns false if the tag has no resource mapping.
+func (c *Converter) convertProcessTag(tag jaeger.KeyValue) ([]Attribute, bool) {
+	// jaeger.version looks like "Go-2.30.0" and describes the client SDK
+	if tag.Key == "jaeger.version" {
+		attrs := []Attribute{
+			{Key: "telemetry.sdk.name", Value: AttributeValue{StringValue: "jaeger"}},
+		}
+		language, version, found := strings.Cut(tag.VStr, "-")
+		if found {
+			attrs = append(attrs,
+				Attribute{Key: "telemetry.sdk.language", Value: AttributeValue{StringValue: strings.ToLower(language)}},
+				Attribute{Key: "telemetry.sdk.version", Value: AttributeValue{StringValue: version}},
+			)
+		} else {
+			attrs = append(attrs, Attribute{Key: "telemetry.sdk.version", Value: AttributeValue{StringValue: tag.VStr}})
+		}
+		return attrs, true
+	}
+
+	key, ok := processTagKeys[tag.Key]
+	if !ok {
+		return nil, false
+	}
+
+	attr := c.convertTag(tag)
+	attr.Key = key
+	return []Attribute{attr}, true
+}
+
+// buildResource appends the resource attributes for a span's process to dst:
+// service.name, the converted process tags and Config.ResourceAttributes
+func (c *Converter) buildResource(dst []Attribute, jaegerSpan *jaeger.Span) []Attribute {
+	serviceNameFound := false
+	if jaegerSpan.Process != nil {
+		// Add service.name from Process.ServiceName (most important)
+		if jaegerSpan.Process.ServiceName != "" {
+			dst = append(dst, Attribute{
+				Key:   "service.name",
+				Value: AttributeValue{StringValue: jaegerSpan.Process.ServiceName},
+			})
+			serviceNameFound = true
+		}
+
+		// Map well-known process tags to semantic convention keys, keep the rest verbatim
+		for _, tag := range jaegerSpan.Process.Tags {
+			if attrs, ok := c.convertProcessTag(tag); ok {
+				dst = append(dst, attrs...)
+				continue
+			}
+			// Skip a duplicate service.name already taken from Process.ServiceName
+			if tag.Key == "service.name" {
+				if serviceNameFound {
+					continue
+				}
+				serviceNameFound = true
+			}
+			dst = c.appendTagAttributes(dst, tag, c.convertTag(tag))
+		}
+	}
+
+	// Ensure service.name is always present
+	if !serviceNameFound {
+		dst = append(dst, Attribute{
+			Key:   "service.name",
+			Value: AttributeValue{StringValue: c.fallbackServiceName(jaegerSpan)},
+		})
+	}
+	return mergeAttributes(collapseRepeatedKeys(dst), c.resourceAttrs)
+}
+
+// resourceKey builds a grouping key from resource attributes, so spans from
+// identical processes share one ResourceSpans
+func resourceKey(attrs []Attribute) string {
+	var sb strings.Builder
+	for _, attr := range attrs {
+		sb.WriteByte(0)
+		sb.WriteString(attr.Key)
+		sb.WriteByte('=')
+		sb.WriteString(attributeValueString(attr.Value))
+	}
+	return sb.String()
+}
+
+// attributeValueString renders an attribute value for use in grouping keys
+func attributeValueString(v AttributeValue) string {
+	switch {
+	case v.BoolValue != nil:
+		return fmt.Sprintf("b:%t", *v.BoolValue)
+	case v.IntValue != nil:
+		return fmt.Sprintf("i:%d", *v.IntValue)
+	case v.DoubleValue != nil:
+		return fmt.Sprintf("d:%g", *v.DoubleValue)
+	case v.BytesValue != "":
+		return "x:" + v.BytesValue
+	case v.ArrayValue != nil:
+		var sb strings.Builder
+		sb.WriteString("a:[")
+		for _, value := range v.ArrayValue.Values {
+			sb.WriteString(attributeValueString(value))
+			sb.WriteByte(0)
+		}
+		sb.WriteByte(']')
+		return sb.String()
+	case v.KvlistValue != nil:
+		var sb strings.Builder
+		sb.WriteString("m:{")
+		for _, kv := range v.KvlistValue.Values {
+			sb.WriteString(kv.Key)
+			sb.WriteByte(0)
+			sb.WriteString(attributeValueString(kv.Value))
+			sb.WriteByte(0)
+		}
+		sb.WriteByte('}')
+		return sb.String()
+	default:
+		return "s:" + v.StringValue
+	}
+}
+
+// fallbackServiceName names the service of a span whose process carries no
+// service name: the ServiceFromTag tag (process tags first, then span tags),
+// else DefaultService, else "unknown". A name taken from a tag is redacted or
+// hashed like the tag itself.
+func (c *Converter) fallbackServiceName(span *jaeger.Span) string {
+	if key := c.config.ServiceFromTag; key != "" {
+		var tags []jaeger.KeyValue
+		if span.Process != nil {
+			tags = append(tags, span.Process.Tags...)
+		}
+		tags = append(tags, span.Tags...)
+		for _, tag := range tags {
+			if tag.Key == key && tag.AsString() != "" {
+				return c.scrubbedString(tag)
+			}
+		}
+	}
+	if c.config.DefaultService != "" {
+		return c.config.DefaultService
+	}
+	return "unknown"
+}
+
+// unknownService reports whether a span's resource would get the "unknown"
+// service.name: its process names no service and neither ServiceFromTag nor
+// DefaultService supplies one
+func (c *Converter) unknownService(span *jaeger.Span) bool {
+	if process := span.Process; process != nil {
+		if process.ServiceName != "" {
+			return false
+		}
+		for _, tag := range process.Tags {
+			if tag.Key == "service.name" {
+				return false
+			}
+		}
+	}
+	if _, ok := c.config.ResourceAttributes["service.name"]; ok {
+		return false
+	}
+	return c.fallbackServiceName(span) == "unknown"
+}
+
+// sortedAttributes turns a string map into string attributes ordered by key
+func sortedAttributes(values map[string]string) []Attribute {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]Attribute, 0, len(keys))
+	for _, key := range keys {
+		attrs = append(attrs, Attribute{Key: key, Value: AttributeValue{StringValue: values[key]}})
+	}
+	return attrs
+}
+
+// mergeAttributes appends extra to attrs, replacing any attribute in attrs
+// that has the same key
+func mergeAttributes(attrs, extra []Attribute) []Attribute {
+	if len(extra) == 0 {
+		return attrs
+	}
+	merged := attrs[:0]
+	for _, attr := range attrs {
+		overridden := false
+		for _, e := range extra {
+			if e.Key == attr.Key {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			merged = append(merged, attr)
+		}
+	}
+	return append(merged, extra...)
+}
+
+// collapseRepeatedKeys merges attributes that share a key, as Jaeger uses
+// repeated tags for list values, into one arrayValue attribute at the
+// position of the first occurrence. Attributes with unique keys are left as
+// they are.
+func collapseRepeatedKeys(attrs []Attribute) []Attribute {
+	if len(attrs) < 2 {
+		return attrs
+	}
+
+	counts := make(map[string]int, len(attrs))
+	repeated := false
+	for _, attr := range attrs {
+		counts[attr.Key]++
+		repeated = repeated || counts[attr.Key] > 1
+	}
+	if !repeated {
+		return attrs
+	}
+
+	collapsed := attrs[:0]
+	arrays := make(map[string]int) // key -> index in collapsed
+	for _, attr := range attrs {
+		if counts[attr.Key] == 1 {
+			collapsed = append(collapsed, attr)
+			continue
+		}
+		if i, ok := arrays[attr.Key]; ok {
+			collapsed[i].Value.ArrayValue.Values = append(collapsed[i].Value.ArrayValue.Values, attr.Value)
+			continue
+		}
+		arrays[attr.Key] = len(collapsed)
+		collapsed = append(collapsed, Attribute{
+			Key:   attr.Key,
+			Value: AttributeValue{ArrayValue: &ArrayValue{Values: []AttributeValue{attr.Value}}},
+		})
+	}
+	return collapsed
+}
+
+// spanServiceName extracts the service.name resource attribute from a span
+func spanServiceName(span *OTLPSpan) string {
+	for _, attr := range span.Resource {
+		if attr.Key == "service.name" {
+			return attr.Value.StringValue
+		}
+	}
+	return "unknown"
+}
+
+// appendRowAttributes appends a span's attributes followed by its resource
+// attributes to dst, for outputs that keep both in one list (Arrow and CSV
+// rows). A key set on both sides is kept once, from the side chosen by
+// Config.AttrPrecedence; the number of attributes dropped that way is
+// returned. An instrumentation scope is added back as otel.scope.* attributes.
+func (c *Converter) appendRowAttributes(dst []Attribute, span *OTLPSpan) ([]Attribute, int) {
+	if span.Scope.Name != "" {
+		dst = append(dst, Attribute{Key: "otel.scope.name", Value: AttributeValue{StringValue: span.Scope.Name}})
+	}
+	if span.Scope.Version != "" {
+		dst = append(dst, Attribute{Key: "otel.scope.version", Value: AttributeValue{StringValue: span.Scope.Version}})
+	}
+	if len(span.Resource) == 0 {
+		return append(dst, span.Attributes...), 0
+	}
+
+	resourceWins := c.config.AttrPrecedence == "resource"
+	dropped := 0
+	for _, attr := range span.Attributes {
+		if resourceWins && hasAttribute(span.Resource, attr.Key) {
+			dropped++
+			continue
+		}
+		dst = append(dst, attr)
+	}
+	for _, attr := range span.Resource {
+		if !resourceWins && hasAttribute(span.Attributes, attr.Key) {
+			dropped++
+			continue
+		}
+		dst = append(dst, attr)
+	}
+	return dst, dropped
+}
+
+// RowAttributes returns a span's attributes merged with its resource
+// attributes the way Arrow and CSV rows store them
+func (c *Converter) RowAttributes(span *OTLPSpan) []Attribute {
+	attrs, _ := c.appendRowAttributes(nil, span)
+	return attrs
+}
+
+// hasAttribute reports whether attrs contains key
+func hasAttribute(attrs []Attribute, key string) bool {
+	for i := range attrs {
+		if attrs[i].Key == key {
+			return true
+		}
+	}
+	return false
+}
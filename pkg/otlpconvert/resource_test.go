@@ -0,0 +1,149 @@
+package otlpconvert
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	jaeger "github.com/jaegertracing/jaeger/model"
+)
+
+// TestRowAttributes checks a key set by both a span tag and a process tag is
+// kept once in Arrow and CSV rows, from the side chosen by AttrPrecedence
+func TestRowAttributes(t *testing.T) {
+	tests := []struct {
+		name        string
+		precedence  string
+		spanTags    []jaeger.KeyValue
+		processTags []jaeger.KeyValue
+		want        string // key=value of the row attributes in order
+		wantDropped int
+	}{
+		{
+			name:        "no overlap",
+			spanTags:    []jaeger.KeyValue{jaeger.String("a", "span")},
+			processTags: []jaeger.KeyValue{jaeger.String("host", "h1")},
+			want:        "a=span,service.name=svc,host=h1",
+		},
+		{
+			name:        "span wins by default",
+			spanTags:    []jaeger.KeyValue{jaeger.String("host", "span-host"), jaeger.String("a", "1")},
+			processTags: []jaeger.KeyValue{jaeger.String("host", "process-host"), jaeger.String("b", "2")},
+			want:        "host=span-host,a=1,service.name=svc,b=2",
+			wantDropped: 1,
+		},
+		{
+			name:        "span wins",
+			precedence:  "span",
+			spanTags:    []jaeger.KeyValue{jaeger.String("host", "span-host")},
+			processTags: []jaeger.KeyValue{jaeger.String("host", "process-host")},
+			want:        "host=span-host,service.name=svc",
+			wantDropped: 1,
+		},
+		{
+			name:        "resource wins",
+			precedence:  "resource",
+			spanTags:    []jaeger.KeyValue{jaeger.String("host", "span-host"), jaeger.String("a", "1")},
+			processTags: []jaeger.KeyValue{jaeger.String("host", "process-host"), jaeger.String("b", "2")},
+			want:        "a=1,service.name=svc,host=process-host,b=2",
+			wantDropped: 1,
+		},
+		{
+			name:        "span tag named service.name",
+			precedence:  "resource",
+			spanTags:    []jaeger.KeyValue{jaeger.String("service.name", "tagged")},
+			want:        "service.name=svc",
+			wantDropped: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(Config{AttrPrecedence: tt.precedence})
+			span := testSpan(1)
+			span.Tags = tt.spanTags
+			span.Process = &jaeger.Process{ServiceName: "svc", Tags: tt.processTags}
+			otlp := c.ConvertJaegerSpan(span)
+
+			attrs, dropped := c.appendRowAttributes(nil, otlp)
+			var got []string
+			for _, a := range attrs {
+				got = append(got, a.Key+"="+a.Value.StringValue)
+			}
+			if strings.Join(got, ",") != tt.want {
+				t.Errorf("row attributes = %s, want %s", strings.Join(got, ","), tt.want)
+			}
+			if dropped != tt.wantDropped {
+				t.Errorf("dropped = %d, want %d", dropped, tt.wantDropped)
+			}
+			if rows := c.RowAttributes(otlp); len(rows) != len(attrs) {
+				t.Errorf("RowAttributes returned %d attributes, want %d", len(rows), len(attrs))
+			}
+		})
+	}
+}
+
+// TestRowAttributesCSV checks the written CSV row keeps one value for a
+// shared key and the run counts the conflict
+func TestRowAttributesCSV(t *testing.T) {
+	dir := t.TempDir()
+	c := New(Config{
+		OutputFile:    filepath.Join(dir, "out"),
+		OutputFormat:  "csv",
+		ValueEncoding: "raw",
+		ValueShape:    "span",
+		WriteInterval: 100,
+	})
+	span := testSpan(1)
+	span.Tags = []jaeger.KeyValue{jaeger.String("host", "span-host")}
+	span.Process = &jaeger.Process{ServiceName: "svc", Tags: []jaeger.KeyValue{jaeger.String("host", "process-host")}}
+	runPipeline(t, c, []BadgerEntry{marshalEntry(t, span)}, 1)
+
+	data, err := os.ReadFile(filepath.Join(dir, "out.batch_0000.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "span-host") || strings.Contains(string(data), "process-host") {
+		t.Errorf("CSV output should hold span-host only:\n%s", data)
+	}
+	if got := c.AttrConflicts(); got != 1 {
+		t.Errorf("attribute conflicts = %d, want 1", got)
+	}
+}
+
+// TestServiceFromTagScrubbed checks a service name taken from a redacted or
+// hashed tag is scrubbed like the tag's own attribute
+func TestServiceFromTagScrubbed(t *testing.T) {
+	sum := sha256.Sum256([]byte("checkout"))
+	tests := []struct {
+		name   string
+		config Config
+		want   string
+	}{
+		{name: "plain", config: Config{}, want: "checkout"},
+		{name: "redacted", config: Config{RedactKeys: []string{"app"}}, want: redactedValue},
+		{name: "hashed", config: Config{HashKeys: []string{"app"}}, want: hex.EncodeToString(sum[:])},
+		{name: "hashed by renamed key", config: Config{HashKeys: []string{"app.name"}, RenameMap: map[string]string{"app": "app.name"}}, want: hex.EncodeToString(sum[:])},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.ServiceFromTag = "app"
+			c := New(tt.config)
+			span := testSpan(1)
+			span.Process = &jaeger.Process{}
+			span.Tags = append(span.Tags, jaeger.String("app", "checkout"))
+
+			otlp := c.ConvertJaegerSpan(span)
+			service, _ := attr(otlp.Resource, "service.name")
+			if service.StringValue != tt.want {
+				t.Errorf("service.name = %q, want %q", service.StringValue, tt.want)
+			}
+			tag, _ := attr(otlp.Attributes, c.attributeKey("app"))
+			if tag.StringValue != tt.want {
+				t.Errorf("tag attribute = %q, want %q", tag.StringValue, tt.want)
+			}
+		})
+	}
+}
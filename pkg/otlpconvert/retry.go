@@ -0,0 +1,110 @@
+package otlpconvert
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// writeRetryBackoff is the delay before the first retry; it doubles on
+// each further attempt
+const writeRetryBackoff = 100 * time.Millisecond
+
+// writeWithRetry calls write up to 1+WriteRetries times, backing off
+// exponentially between attempts, to ride out transient filesystem errors
+// such as NFS hiccups. The directory of filename is created first if needed.
+// write gets a temporary path next to filename (<filename>.tmp), which is
+// renamed over filename once it succeeds and removed if it fails, so readers
+// never see a partial file. An s3:// filename is written to a temporary local
+// file instead and uploaded, see uploadFile. A full disk is not retried: it
+// stops the run (see DiskFull). It returns the last error if every attempt
+// fails.
+func (c *Converter) writeWithRetry(filename string, write func(path string) error) error {
+	writeOnce := writeFile
+	if IsS3URL(filename) {
+		writeOnce = c.uploadFile
+	}
+	backoff := writeRetryBackoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = writeOnce(filename, write); err == nil {
+			return nil
+		}
+		if c.checkDiskFull(filename, err) {
+			return err
+		}
+		if attempt >= c.config.WriteRetries {
+			return err
+		}
+		slog.Warn("write failed, retrying", "filename", filename, "attempt", attempt+1, "backoff", backoff.String(), "error", err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// checkDiskFull reports whether err is a full disk, and if so stops the run
+func (c *Converter) checkDiskFull(filename string, err error) bool {
+	if !errors.Is(err, syscall.ENOSPC) {
+		return false
+	}
+	if !c.diskFull.Swap(true) {
+		slog.Error("output disk is full, stopping the run", "filename", filename, "error", err)
+	}
+	c.stopReading()
+	return true
+}
+
+// writeFile makes one attempt at writing filename through a temporary file
+// renamed into place
+func writeFile(filename string, write func(path string) error) error {
+	tmpFile := filename + ".tmp"
+	err := ensureParentDir(filename)
+	if err == nil {
+		if err = write(tmpFile); err == nil {
+			err = os.Rename(tmpFile, filename)
+		}
+	}
+	if err != nil {
+		os.Remove(tmpFile)
+	}
+	return err
+}
+
+// uploadFile makes one attempt at writing an s3:// object: the writers need
+// a file, so write fills a temporary file in os.TempDir, which is uploaded
+// and removed. Only the batch in flight is held locally, so TMPDIR may be a
+// small tmpfs. The object appears only once the upload completes.
+func (c *Converter) uploadFile(filename string, write func(path string) error) error {
+	if c.s3Err != nil {
+		return c.s3Err
+	}
+	bucket, key, err := ParseS3URL(filename)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp("", "otlp-upload-*")
+	if err != nil {
+		return err
+	}
+	tmpFile := tmp.Name()
+	tmp.Close()
+	defer os.Remove(tmpFile)
+
+	if err := write(tmpFile); err != nil {
+		return err
+	}
+	return c.s3.PutObjectFile(bucket, key, tmpFile)
+}
+
+// ensureParentDir creates the directory holding filename, so a nested
+// -output such as out/traces_otlp works without creating out/ beforehand
+func ensureParentDir(filename string) error {
+	dir := filepath.Dir(filename)
+	if dir == "." {
+		return nil
+	}
+	return os.MkdirAll(dir, 0o755)
+}
@@ -0,0 +1,52 @@
+package otlpconvert
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestNestedOutputPath checks batch files and the checkpoint are written
+// below output directories that do not exist yet
+func TestNestedOutputPath(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string // relative to a fresh temporary directory
+		format string
+		want   []string
+	}{
+		{name: "one level", output: "out/traces", format: "json", want: []string{"out/traces.batch_0000.otlp.json"}},
+		{name: "several levels", output: "a/b/c/traces", format: "json", want: []string{"a/b/c/traces.batch_0000.otlp.json"}},
+		{name: "existing directory", output: "traces", format: "json", want: []string{"traces.batch_0000.otlp.json"}},
+		{
+			name:   "both formats",
+			output: "x/y/traces",
+			format: "both",
+			want:   []string{"x/y/traces.batch_0000.otlp.json", "x/y/traces.batch_0000.arrow"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			output := filepath.Join(dir, tt.output)
+			c := New(Config{
+				OutputFile:     output,
+				OutputFormat:   tt.format,
+				ValueEncoding:  "raw",
+				ValueShape:     "span",
+				WriteInterval:  100,
+				CheckpointFile: filepath.Join(dir, "ckpt", "run.checkpoint"),
+			})
+			runPipeline(t, c, []BadgerEntry{marshalEntry(t, testSpan(1))}, 1)
+
+			for _, name := range append(tt.want, "ckpt/run.checkpoint") {
+				if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+					t.Errorf("%s: %v", name, err)
+				}
+			}
+			if matches, _ := filepath.Glob(filepath.Join(filepath.Dir(output), "*.tmp")); len(matches) != 0 {
+				t.Errorf("temporary files left: %v", matches)
+			}
+		})
+	}
+}
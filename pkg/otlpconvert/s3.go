@@ -0,0 +1,382 @@
+package otlpconvert
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// s3PartSize is the part size of multipart uploads; objects up to this size
+// are sent with a single PUT. S3 allows 10,000 parts, so objects up to
+// about 160 GB can be uploaded.
+const s3PartSize = 16 << 20
+
+// emptyPayloadHash is the SHA-256 of an empty request body
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// S3Client sends S3 requests signed with AWS Signature Version 4. It covers
+// what the converter needs, reading and writing whole objects, without
+// depending on the AWS SDK.
+type S3Client struct {
+	accessKey    string
+	secretKey    string
+	sessionToken string // for temporary credentials, may be empty
+	region       string
+	endpoint     string // custom endpoint with path-style addressing; empty for AWS
+	partSize     int    // multipart upload part size, s3PartSize
+	http         *http.Client
+	now          func() time.Time
+}
+
+// NewS3ClientFromEnv returns a client using the standard AWS environment
+// variables: AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and optionally
+// AWS_SESSION_TOKEN, AWS_REGION (or AWS_DEFAULT_REGION, default us-east-1)
+// and AWS_ENDPOINT_URL_S3 (or AWS_ENDPOINT_URL) for S3-compatible stores
+// such as MinIO.
+func NewS3ClientFromEnv() (*S3Client, error) {
+	client := &S3Client{
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		region:       firstEnv("AWS_REGION", "AWS_DEFAULT_REGION"),
+		endpoint:     strings.TrimSuffix(firstEnv("AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL"), "/"),
+		partSize:     s3PartSize,
+		http:         http.DefaultClient,
+		now:          time.Now,
+	}
+	if client.accessKey == "" || client.secretKey == "" {
+		return nil, fmt.Errorf("s3:// needs AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY in the environment")
+	}
+	if client.region == "" {
+		client.region = "us-east-1"
+	}
+	return client, nil
+}
+
+// firstEnv returns the first non-empty environment variable of names
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// IsS3URL reports whether name is an s3://bucket/key URL
+func IsS3URL(name string) bool {
+	return strings.HasPrefix(name, "s3://")
+}
+
+// ParseS3URL splits an s3://bucket/key URL
+func ParseS3URL(rawURL string) (bucket, key string, err error) {
+	bucket, key, _ = strings.Cut(strings.TrimPrefix(rawURL, "s3://"), "/")
+	if !IsS3URL(rawURL) || bucket == "" || key == "" {
+		return "", "", fmt.Errorf("invalid S3 URL %q (want s3://bucket/key)", rawURL)
+	}
+	return bucket, key, nil
+}
+
+// ObjectURL returns the HTTPS URL of an object. AWS buckets are addressed
+// virtual-hosted style unless the name has a dot, which would not match
+// the wildcard certificate; custom endpoints use path style.
+func (s *S3Client) ObjectURL(bucket, key string) string {
+	path := "/" + s3EscapePath(key)
+	switch {
+	case s.endpoint != "":
+		return s.endpoint + "/" + bucket + path
+	case strings.Contains(bucket, "."):
+		return "https://s3." + s.region + ".amazonaws.com/" + bucket + path
+	default:
+		return "https://" + bucket + ".s3." + s.region + ".amazonaws.com" + path
+	}
+}
+
+// SignRequest adds the SigV4 headers to a request whose body has the given
+// SHA-256 hex digest, or no body if payloadHash is empty. The host, the
+// Range header if set and the x-amz-* headers are signed.
+func (s *S3Client) SignRequest(req *http.Request, payloadHash string) {
+	if payloadHash == "" {
+		payloadHash = emptyPayloadHash
+	}
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	scope := amzDate[:8] + "/" + s.region + "/s3/aws4_request"
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "range" || strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		s3CanonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), amzDate[:8])
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// PutObjectFile uploads a local file as an object, in parts if it is larger
+// than the part size
+func (s *S3Client) PutObjectFile(bucket, key, filename string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if info.Size() <= int64(s.partSize) {
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return err
+		}
+		_, err = s.do(http.MethodPut, bucket, key, nil, data)
+		return err
+	}
+	return s.putMultipart(bucket, key, file)
+}
+
+// putMultipart uploads r in parts of the part size, aborting the upload on error
+// so no orphaned parts are billed
+func (s *S3Client) putMultipart(bucket, key string, r io.Reader) error {
+	resp, err := s.do(http.MethodPost, bucket, key, url.Values{"uploads": {""}}, nil)
+	if err != nil {
+		return err
+	}
+	var initiated struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.Unmarshal(resp, &initiated); err != nil || initiated.UploadID == "" {
+		return fmt.Errorf("s3://%s/%s: unexpected multipart upload response", bucket, key)
+	}
+	upload := url.Values{"uploadId": {initiated.UploadID}}
+
+	err = s.uploadParts(bucket, key, initiated.UploadID, r)
+	if err == nil {
+		return nil
+	}
+	// Best effort: the upload error is what matters
+	s.do(http.MethodDelete, bucket, key, upload, nil)
+	return err
+}
+
+// uploadParts sends the parts of a multipart upload and completes it
+func (s *S3Client) uploadParts(bucket, key, uploadID string, r io.Reader) error {
+	type part struct {
+		PartNumber int    `xml:"PartNumber"`
+		ETag       string `xml:"ETag"`
+	}
+	var parts []part
+	buf := make([]byte, s.partSize)
+	for number := 1; ; number++ {
+		n, err := io.ReadFull(r, buf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {uploadID}}
+		etag, err := s.putPart(bucket, key, query, buf[:n])
+		if err != nil {
+			return err
+		}
+		parts = append(parts, part{PartNumber: number, ETag: etag})
+		if n < len(buf) {
+			break
+		}
+	}
+
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name `xml:"CompleteMultipartUpload"`
+		Parts   []part   `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return err
+	}
+	_, err = s.do(http.MethodPost, bucket, key, url.Values{"uploadId": {uploadID}}, body)
+	return err
+}
+
+// putPart uploads one part and returns its ETag
+func (s *S3Client) putPart(bucket, key string, query url.Values, data []byte) (string, error) {
+	req, err := s.newRequest(http.MethodPut, bucket, key, query, data)
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if err := s3ResponseError(resp, bucket, key); err != nil {
+		return "", err
+	}
+	return resp.Header.Get("ETag"), nil
+}
+
+// DeleteObject removes an object; a missing object is not an error
+func (s *S3Client) DeleteObject(bucket, key string) error {
+	_, err := s.do(http.MethodDelete, bucket, key, nil, nil)
+	return err
+}
+
+// do sends a signed request and returns the response body
+func (s *S3Client) do(method, bucket, key string, query url.Values, body []byte) ([]byte, error) {
+	req, err := s.newRequest(method, bucket, key, query, body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := s3ResponseError(resp, bucket, key); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// newRequest builds a signed request for an object
+func (s *S3Client) newRequest(method, bucket, key string, query url.Values, body []byte) (*http.Request, error) {
+	target := s.ObjectURL(bucket, key)
+	if len(query) > 0 {
+		target += "?" + s3CanonicalQuery(query)
+	}
+	req, err := http.NewRequest(method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	s.SignRequest(req, sha256Hex(body))
+	return req, nil
+}
+
+// s3ResponseError turns a failed response into an error carrying the S3
+// error code and message
+func s3ResponseError(resp *http.Response, bucket, key string) error {
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	var s3err struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	xml.Unmarshal(data, &s3err)
+
+	detail := fmt.Sprintf("HTTP %d", resp.StatusCode)
+	if s3err.Code != "" {
+		detail += " " + s3err.Code
+	}
+	if s3err.Message != "" {
+		detail += ": " + s3err.Message
+	}
+	if resp.StatusCode == http.StatusForbidden {
+		detail += " (check the AWS credentials and that they may access the bucket)"
+	}
+	return fmt.Errorf("s3://%s/%s: %w", bucket, key, errors.New(detail))
+}
+
+// s3CanonicalQuery encodes query parameters sorted by name, the form SigV4
+// signs; valueless parameters such as ?uploads keep their "="
+func s3CanonicalQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var pairs []string
+	for _, name := range names {
+		values := append([]string(nil), query[name]...)
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, s3Escape(name)+"="+s3Escape(value))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// s3EscapePath escapes an object key for the URL path, keeping slashes
+func s3EscapePath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = s3Escape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// s3Escape percent-encodes everything but the RFC 3986 unreserved
+// characters, as SigV4 requires
+func s3Escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if ('A' <= ch && ch <= 'Z') || ('a' <= ch && ch <= 'z') || ('0' <= ch && ch <= '9') ||
+			ch == '-' || ch == '_' || ch == '.' || ch == '~' {
+			b.WriteByte(ch)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,27 @@
+package otlpconvert
+
+import (
+	"hash/fnv"
+	"math"
+)
+
+// sampleThreshold converts a Config.Sample rate into the hash value below
+// which a trace is kept. It returns 0 when sampling is off (rate 0 or >= 1).
+func sampleThreshold(rate float64) uint64 {
+	if rate <= 0 || rate >= 1 {
+		return 0
+	}
+	return uint64(rate * math.MaxUint64)
+}
+
+// sampled reports whether span belongs to a trace kept by Config.Sample. The
+// decision hashes the trace ID only, so every span of a trace gets the same
+// answer regardless of which worker or run sees it.
+func (c *Converter) sampled(span *OTLPSpan) bool {
+	if c.sampleThreshold == 0 {
+		return true
+	}
+	h := fnv.New64a()
+	h.Write(span.TraceIDBytes[:])
+	return h.Sum64() < c.sampleThreshold
+}
@@ -0,0 +1,59 @@
+package otlpconvert
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateSample(t *testing.T) {
+	tests := []struct {
+		sample  float64
+		wantErr bool
+	}{
+		{sample: 1},
+		{sample: 0.1},
+		{sample: 0, wantErr: true},
+		{sample: -0.5, wantErr: true},
+		{sample: 1.5, wantErr: true},
+	}
+	for _, tt := range tests {
+		config := Config{
+			NumWorkers:    1,
+			BatchSize:     1,
+			EntryQueue:    1,
+			ResultQueue:   1,
+			IOWorkers:     1,
+			InputWorkers:  1,
+			InputFormat:   "badger",
+			Generate:      10,
+			OutputFile:    "out",
+			OutputFormat:  "json",
+			ValueEncoding: "raw",
+			ValueShape:    "spanlist",
+			WriteInterval: 1000,
+			FlushInterval: time.Second,
+			LogFormat:     "text",
+			LogLevel:      "info",
+			Sample:        tt.sample,
+		}
+		err := config.Validate()
+		if tt.wantErr {
+			if err == nil || !strings.Contains(err.Error(), "-sample") {
+				t.Errorf("Sample %g: error = %v, want a -sample error", tt.sample, err)
+			}
+		} else if err != nil {
+			t.Errorf("Sample %g: unexpected error %v", tt.sample, err)
+		}
+	}
+}
+
+func TestSampleUnsetKeepsAll(t *testing.T) {
+	c := New(Config{})
+	for i := uint64(1); i <= 100; i++ {
+		span := c.ConvertJaegerSpan(testSpan(i))
+		if !c.sampled(span) {
+			t.Fatalf("span %d sampled out with Sample unset", i)
+		}
+	}
+}
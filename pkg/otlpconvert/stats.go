@@ -0,0 +1,136 @@
+package otlpconvert
+
+import (
+	"sync"
+	"time"
+
+	jaeger "github.com/jaegertracing/jaeger/model"
+)
+
+// spanKinds maps Jaeger span.kind tag values to OTLP span kinds. Spans
+// without the tag get Config.SpanKindDefault.
+var spanKinds = map[string]string{
+	"server":   "SPAN_KIND_SERVER",
+	"client":   "SPAN_KIND_CLIENT",
+	"producer": "SPAN_KIND_PRODUCER",
+	"consumer": "SPAN_KIND_CONSUMER",
+	"internal": "SPAN_KIND_INTERNAL",
+}
+
+// otlpSpanKind returns the OTLP kind for a span.kind tag value; unknown values
+// make the span SPAN_KIND_INTERNAL
+func otlpSpanKind(value string) string {
+	if kind, ok := spanKinds[value]; ok {
+		return kind
+	}
+	return "SPAN_KIND_INTERNAL"
+}
+
+// ExportStats summarizes an export without converting it (the CLI's -stats
+// mode). Workers fill it through StatsWorker.
+type ExportStats struct {
+	Spans       int64
+	ParseErrors int64
+	Errored     int64            // spans with error=true or an error.type tag
+	Services    map[string]int64 // spans per service.name
+	Kinds       map[string]int64 // spans per OTLP span kind
+	Earliest    time.Time        // earliest span start
+	Latest      time.Time        // latest span end
+
+	mu sync.Mutex
+}
+
+// NewExportStats returns empty stats ready for StatsWorker
+func NewExportStats() *ExportStats {
+	return &ExportStats{
+		Services: make(map[string]int64),
+		Kinds:    make(map[string]int64),
+	}
+}
+
+// StatsWorker parses entries from entryChan and adds them to stats. It only
+// unmarshals the Jaeger span(s) and reads a few fields; no OTLP spans are built.
+// Counts are gathered per worker and merged into stats once entryChan closes.
+func (c *Converter) StatsWorker(entryChan <-chan BadgerEntry, stats *ExportStats, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	local := NewExportStats()
+	for entry := range entryChan {
+		valueBytes, err := c.decodeValue(entry.Value)
+		if err != nil {
+			local.ParseErrors++
+			continue
+		}
+		spans, err := c.unmarshalSpans(valueBytes)
+		if err != nil {
+			local.ParseErrors++
+			continue
+		}
+		for _, span := range spans {
+			local.add(c, span)
+		}
+	}
+
+	stats.merge(local)
+}
+
+// add counts one span
+func (s *ExportStats) add(c *Converter, span *jaeger.Span) {
+	s.Spans++
+
+	service := ""
+	if span.Process != nil {
+		service = span.Process.ServiceName
+	}
+	if service == "" {
+		service = c.fallbackServiceName(span)
+	}
+	s.Services[service]++
+
+	kind := c.defaultKind
+	errored := false
+	for _, tag := range span.Tags {
+		switch tag.Key {
+		case "span.kind":
+			kind = otlpSpanKind(tag.VStr)
+		case "error":
+			errored = errored || tag.VBool || tag.VStr == "true"
+		case "error.type":
+			errored = true
+		}
+	}
+	s.Kinds[kind]++
+	if errored {
+		s.Errored++
+	}
+
+	end := span.StartTime.Add(span.Duration)
+	if s.Earliest.IsZero() || span.StartTime.Before(s.Earliest) {
+		s.Earliest = span.StartTime
+	}
+	if end.After(s.Latest) {
+		s.Latest = end
+	}
+}
+
+// merge adds the counts from other into s
+func (s *ExportStats) merge(other *ExportStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Spans += other.Spans
+	s.ParseErrors += other.ParseErrors
+	s.Errored += other.Errored
+	for service, n := range other.Services {
+		s.Services[service] += n
+	}
+	for kind, n := range other.Kinds {
+		s.Kinds[kind] += n
+	}
+	if !other.Earliest.IsZero() && (s.Earliest.IsZero() || other.Earliest.Before(s.Earliest)) {
+		s.Earliest = other.Earliest
+	}
+	if other.Latest.After(s.Latest) {
+		s.Latest = other.Latest
+	}
+}
@@ -0,0 +1,86 @@
+package otlpconvert
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// writeTraceFiles writes each trace to its own <prefix>.<traceid>.otlp.json
+// (Config.OneFilePerTrace). A trace that spans several batches is merged
+// into the file written for it earlier in the run. Once MaxTraceFiles files
+// exist, spans of further traces are skipped and counted.
+func (c *Converter) writeTraceFiles(prefix string, traces map[string][]*OTLPSpan, batchNum int) {
+	for _, traceID := range c.traceOrder(traces) {
+		c.writeTraceFile(fmt.Sprintf("%s.%s.otlp.json", prefix, traceID), traces, traceID, batchNum)
+	}
+}
+
+// writeTraceFile writes or merges one trace's file. Trace files are written
+// one at a time, so concurrent writers never merge into the same file at once.
+func (c *Converter) writeTraceFile(filename string, traces map[string][]*OTLPSpan, traceID string, batchNum int) {
+	c.traceFilesLock.Lock()
+	defer c.traceFilesLock.Unlock()
+
+	seen := c.traceFiles[filename]
+	if !seen && c.config.MaxTraceFiles > 0 && len(c.traceFiles) >= c.config.MaxTraceFiles {
+		if c.traceFileDrops.Add(int64(len(traces[traceID]))) == int64(len(traces[traceID])) {
+			slog.Warn("trace file limit reached, skipping further traces", "max_trace_files", c.config.MaxTraceFiles)
+		}
+		return
+	}
+	c.traceFiles[filename] = true
+
+	otlpExport, spanCount := buildOTLPExport(traces, []string{traceID}, c.config.SchemaURL)
+	err := c.writeWithRetry(filename, func(path string) error {
+		export := otlpExport
+		if seen {
+			earlier, err := readOTLPJSONFile(filename)
+			if err != nil {
+				return err
+			}
+			export.ResourceSpans = append(earlier.ResourceSpans, export.ResourceSpans...)
+		}
+		return c.writeOTLPJSONFile(path, func(w io.Writer) error {
+			return c.encodeOTLPJSON(w, []OTLPExport{export})
+		})
+	})
+	if err != nil {
+		c.lostBatches.Add(1)
+		slog.Error("failed to write trace file", "batch", batchNum, "filename", filename, "spans", spanCount, "error", err)
+		return
+	}
+
+	c.statsLock.Lock()
+	c.totalSpans += spanCount
+	c.statsLock.Unlock()
+
+	slog.Debug("wrote trace file", "batch", batchNum, "filename", filename, "spans", spanCount, "merged", seen)
+}
+
+// readOTLPJSONFile decodes an OTLP JSON file written earlier in the run
+func readOTLPJSONFile(filename string) (OTLPExport, error) {
+	var otlpExport OTLPExport
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return otlpExport, err
+	}
+	err = json.Unmarshal(data, &otlpExport)
+	return otlpExport, err
+}
+
+// TraceFiles returns how many per-trace files were written with
+// Config.OneFilePerTrace
+func (c *Converter) TraceFiles() int {
+	c.traceFilesLock.Lock()
+	defer c.traceFilesLock.Unlock()
+	return len(c.traceFiles)
+}
+
+// TraceFileDrops returns how many spans were skipped because MaxTraceFiles
+// was reached
+func (c *Converter) TraceFileDrops() int64 {
+	return c.traceFileDrops.Load()
+}
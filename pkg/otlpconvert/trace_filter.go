@@ -0,0 +1,34 @@
+package otlpconvert
+
+import "fmt"
+
+// normalizeTraceID converts a user-supplied trace ID to the 32-character
+// lowercase hex form used in the output. Jaeger UIs often print IDs without
+// leading zeros, so shorter IDs are left-padded.
+func normalizeTraceID(id string) (string, error) {
+	normalized, err := normalizeHexID(id, 16)
+	if err != nil {
+		return "", fmt.Errorf("trace %w", err)
+	}
+	return normalized, nil
+}
+
+// traceIDSet normalizes Config.TraceIDs into a set, or nil when every trace
+// is wanted. Validate has already rejected malformed IDs.
+func traceIDSet(ids []string) map[string]bool {
+	if len(ids) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if normalized, err := normalizeTraceID(id); err == nil {
+			set[normalized] = true
+		}
+	}
+	return set
+}
+
+// wantedTrace reports whether span passes Config.TraceIDs
+func (c *Converter) wantedTrace(span *OTLPSpan) bool {
+	return c.traceIDs == nil || c.traceIDs[span.TraceID]
+}
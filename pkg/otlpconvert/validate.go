@@ -0,0 +1,164 @@
+package otlpconvert
+
+import (
+	"encoding/base64"
+	"math"
+	"strconv"
+	"unicode/utf8"
+)
+
+// otlpViolation is a kind of OTLP invariant a converted span can break, for
+// Config.ValidateOTLP
+type otlpViolation int
+
+const (
+	violationTraceID      otlpViolation = iota // not 32 hex digits
+	violationSpanID                            // not 16 hex digits
+	violationParentSpanID                      // set but not 16 hex digits
+	violationName                              // empty
+	violationKind                              // not an OTLP SpanKind
+	violationTimestamps                        // unparseable, end before start, or missing from Zipkin
+	violationAttributes                        // empty key or malformed value
+	numViolations
+)
+
+// violationNames are the keys of Converter.OTLPViolations
+var violationNames = [numViolations]string{
+	violationTraceID:      "trace_id",
+	violationSpanID:       "span_id",
+	violationParentSpanID: "parent_span_id",
+	violationName:         "name",
+	violationKind:         "kind",
+	violationTimestamps:   "timestamps",
+	violationAttributes:   "attributes",
+}
+
+// validateSpan checks a converted span against the OTLP invariants, counting
+// each kind of violation once per span. It reports whether the span is valid.
+func (c *Converter) validateSpan(span *OTLPSpan) bool {
+	var found [numViolations]bool
+	found[violationTraceID] = !isHexID(span.TraceID, 32)
+	found[violationSpanID] = !isHexID(span.SpanID, 16)
+	found[violationParentSpanID] = span.ParentSpanID != "" && !isHexID(span.ParentSpanID, 16)
+	found[violationName] = span.Name == ""
+	_, knownKind := spanKindValues[span.Kind]
+	found[violationKind] = !knownKind
+
+	start, startErr := strconv.ParseUint(span.StartTimeUnixNano, 10, 64)
+	end, endErr := strconv.ParseUint(span.EndTimeUnixNano, 10, 64)
+	found[violationTimestamps] = startErr != nil || endErr != nil || end < start || span.missingTimestamp
+
+	found[violationAttributes] = !validAttributes(span.Attributes) || !validAttributes(span.Resource)
+	for _, event := range span.Events {
+		if !validAttributes(event.Attributes) {
+			found[violationAttributes] = true
+		}
+	}
+	for _, link := range span.Links {
+		if !validAttributes(link.Attributes) {
+			found[violationAttributes] = true
+		}
+	}
+
+	valid := true
+	for kind, bad := range found {
+		if bad {
+			c.violations[kind].Add(1)
+			valid = false
+		}
+	}
+	if !valid {
+		c.invalidSpans.Add(1)
+	}
+	return valid
+}
+
+// isHexID reports whether id is n lowercase hex digits
+func isHexID(id string, n int) bool {
+	if len(id) != n {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if (id[i] < '0' || id[i] > '9') && (id[i] < 'a' || id[i] > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// validAttributes reports whether every attribute has a key and a
+// well-formed value
+func validAttributes(attrs []Attribute) bool {
+	for _, attr := range attrs {
+		if attr.Key == "" || !validValue(attr.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// validValue reports whether v holds at most one value (an AnyValue is a
+// oneof; an empty string is a string), strings are UTF-8, bytes are base64,
+// doubles are finite (JSON cannot carry NaN or infinity) and nested arrays
+// and maps are well-formed too
+func validValue(v AttributeValue) bool {
+	set := 0
+	if v.StringValue != "" {
+		set++
+		if !utf8.ValidString(v.StringValue) {
+			return false
+		}
+	}
+	if v.BoolValue != nil {
+		set++
+	}
+	if v.IntValue != nil {
+		set++
+	}
+	if v.DoubleValue != nil {
+		set++
+		if math.IsNaN(*v.DoubleValue) || math.IsInf(*v.DoubleValue, 0) {
+			return false
+		}
+	}
+	if v.BytesValue != "" {
+		set++
+		if _, err := base64.StdEncoding.DecodeString(v.BytesValue); err != nil {
+			return false
+		}
+	}
+	if v.ArrayValue != nil {
+		set++
+		for _, elem := range v.ArrayValue.Values {
+			if !validValue(elem) {
+				return false
+			}
+		}
+	}
+	if v.KvlistValue != nil {
+		set++
+		if !validAttributes(v.KvlistValue.Values) {
+			return false
+		}
+	}
+	return set <= 1
+}
+
+// OTLPViolations returns, with Config.ValidateOTLP, how many spans broke each
+// kind of OTLP invariant, keyed trace_id, span_id, parent_span_id, name,
+// kind, timestamps and attributes. Kinds with no violations are left out.
+func (c *Converter) OTLPViolations() map[string]int64 {
+	counts := make(map[string]int64)
+	for kind := range c.violations {
+		if n := c.violations[kind].Load(); n > 0 {
+			counts[violationNames[kind]] = n
+		}
+	}
+	return counts
+}
+
+// InvalidSpans returns how many spans failed Config.ValidateOTLP, dropped
+// with Config.Strict
+func (c *Converter) InvalidSpans() int64 {
+	return c.invalidSpans.Load()
+}
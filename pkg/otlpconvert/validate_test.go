@@ -0,0 +1,76 @@
+package otlpconvert
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// TestValidateZipkinTimestamp checks a Zipkin span without a timestamp is
+// counted as an invalid timestamp and fails validation, while one with a
+// timestamp passes
+func TestValidateZipkinTimestamp(t *testing.T) {
+	tests := []struct {
+		name        string
+		timestamp   int64
+		wantValid   bool
+		wantInvalid int64
+	}{
+		{name: "timestamp", timestamp: 1700000000000000, wantValid: true},
+		{name: "no timestamp", wantInvalid: 1},
+	}
+	for _, tt := range tests {
+		z := ZipkinSpan{
+			TraceID:   "00000000000000010000000000000001",
+			ID:        "0000000000000002",
+			Name:      "op",
+			Timestamp: tt.timestamp,
+			Duration:  1000,
+		}
+		value, err := json.Marshal(z)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Both the library entry point and input entries go through validation
+		t.Run(tt.name+"/ConvertZipkinSpan", func(t *testing.T) {
+			c := New(Config{ValidateOTLP: true})
+			checkZipkinTimestamp(t, c, c.ConvertZipkinSpan(&z), tt.wantValid, tt.wantInvalid)
+		})
+		t.Run(tt.name+"/entry", func(t *testing.T) {
+			c := New(Config{ValidateOTLP: true, InputFormat: "zipkin", ValueEncoding: "raw"})
+			var span *OTLPSpan
+			if spans := c.parseEntry(BadgerEntry{Value: value}); len(spans) == 1 {
+				span = spans[0]
+			}
+			checkZipkinTimestamp(t, c, span, tt.wantValid, tt.wantInvalid)
+		})
+	}
+
+	// The span is dropped instead when invalid timestamps are skipped
+	c := New(Config{SkipInvalidTimestamps: true})
+	if span := c.ConvertZipkinSpan(&ZipkinSpan{TraceID: "0000000000000001", ID: "0000000000000002"}); span != nil {
+		t.Error("span without a timestamp converted despite SkipInvalidTimestamps")
+	}
+}
+
+// checkZipkinTimestamp validates a converted Zipkin span and checks the
+// outcome and the timestamp counters of c
+func checkZipkinTimestamp(t *testing.T, c *Converter, span *OTLPSpan, wantValid bool, wantInvalid int64) {
+	t.Helper()
+	if span == nil {
+		t.Fatal("span not converted")
+	}
+	if got := c.validateSpan(span); got != wantValid {
+		t.Errorf("valid = %v, want %v", got, wantValid)
+	}
+	wantViolations := int64(0)
+	if !wantValid {
+		wantViolations = 1
+	}
+	if got := c.OTLPViolations()["timestamps"]; got != wantViolations {
+		t.Errorf("timestamps violations = %d, want %d", got, wantViolations)
+	}
+	if got := c.InvalidTimestamps(); got != wantInvalid {
+		t.Errorf("invalid timestamps = %d, want %d", got, wantInvalid)
+	}
+}
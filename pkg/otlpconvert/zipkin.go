@@ -0,0 +1,171 @@
+package otlpconvert
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	jaeger "github.com/jaegertracing/jaeger/model"
+)
+
+// ZipkinSpan is a span in the Zipkin v2 JSON format
+type ZipkinSpan struct {
+	TraceID        string             `json:"traceId"`
+	ID             string             `json:"id"`
+	ParentID       string             `json:"parentId,omitempty"`
+	Name           string             `json:"name,omitempty"`
+	Kind           string             `json:"kind,omitempty"`
+	Timestamp      int64              `json:"timestamp,omitempty"` // microseconds since the epoch
+	Duration       int64              `json:"duration,omitempty"`  // microseconds
+	Debug          bool               `json:"debug,omitempty"`
+	LocalEndpoint  *ZipkinEndpoint    `json:"localEndpoint,omitempty"`
+	RemoteEndpoint *ZipkinEndpoint    `json:"remoteEndpoint,omitempty"`
+	Annotations    []ZipkinAnnotation `json:"annotations,omitempty"`
+	Tags           map[string]string  `json:"tags,omitempty"`
+}
+
+// ZipkinEndpoint is the network context of a Zipkin span
+type ZipkinEndpoint struct {
+	ServiceName string `json:"serviceName,omitempty"`
+	IPv4        string `json:"ipv4,omitempty"`
+	IPv6        string `json:"ipv6,omitempty"`
+	Port        int    `json:"port,omitempty"`
+}
+
+// ZipkinAnnotation is a timestamped event on a Zipkin span
+type ZipkinAnnotation struct {
+	Timestamp int64  `json:"timestamp"` // microseconds since the epoch
+	Value     string `json:"value"`
+}
+
+// unmarshalZipkinSpan parses a Zipkin v2 JSON span into a Jaeger span, so it
+// goes through the same conversion (and options) as Jaeger input
+func unmarshalZipkinSpan(data []byte) (*jaeger.Span, error) {
+	var zipkinSpan ZipkinSpan
+	if err := json.Unmarshal(data, &zipkinSpan); err != nil {
+		return nil, err
+	}
+	return zipkinToJaeger(&zipkinSpan)
+}
+
+// zipkinToJaeger maps a Zipkin span onto the Jaeger model the way Jaeger's
+// own Zipkin receiver does: kind becomes a span.kind tag, the local endpoint
+// the process, the remote endpoint peer.* tags and annotations logs
+func zipkinToJaeger(z *ZipkinSpan) (*jaeger.Span, error) {
+	traceID, err := jaeger.TraceIDFromString(z.TraceID)
+	if err != nil {
+		return nil, fmt.Errorf("traceId: %w", err)
+	}
+	spanID, err := jaeger.SpanIDFromString(z.ID)
+	if err != nil {
+		return nil, fmt.Errorf("id: %w", err)
+	}
+
+	span := &jaeger.Span{
+		TraceID:       traceID,
+		SpanID:        spanID,
+		OperationName: z.Name,
+		StartTime:     zipkinTime(z.Timestamp),
+		Duration:      time.Duration(z.Duration) * time.Microsecond,
+		Flags:         jaeger.SampledFlag,
+		Process:       &jaeger.Process{},
+	}
+	if z.Debug {
+		span.Flags |= jaeger.DebugFlag
+	}
+
+	if z.ParentID != "" {
+		parentID, err := jaeger.SpanIDFromString(z.ParentID)
+		if err != nil {
+			return nil, fmt.Errorf("parentId: %w", err)
+		}
+		span.References = append(span.References, jaeger.NewChildOfRef(traceID, parentID))
+	}
+
+	if z.Kind != "" {
+		span.Tags = append(span.Tags, jaeger.String("span.kind", strings.ToLower(z.Kind)))
+	}
+
+	// Zipkin tags are an unordered map, sort them for stable output
+	keys := make([]string, 0, len(z.Tags))
+	for key := range z.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := z.Tags[key]
+		if key == "error" {
+			// Zipkin marks errors with an "error" tag holding the message
+			span.Tags = append(span.Tags, jaeger.Bool("error", true))
+			if value != "" && value != "true" {
+				span.Tags = append(span.Tags, jaeger.String("error.message", value))
+			}
+			continue
+		}
+		span.Tags = append(span.Tags, jaeger.String(key, value))
+	}
+
+	if local := z.LocalEndpoint; local != nil {
+		span.Process.ServiceName = local.ServiceName
+		if local.IPv4 != "" {
+			span.Process.Tags = append(span.Process.Tags, jaeger.String("ip", local.IPv4))
+		} else if local.IPv6 != "" {
+			span.Process.Tags = append(span.Process.Tags, jaeger.String("ip", local.IPv6))
+		}
+	}
+	if remote := z.RemoteEndpoint; remote != nil {
+		if remote.ServiceName != "" {
+			span.Tags = append(span.Tags, jaeger.String("peer.service", remote.ServiceName))
+		}
+		if remote.IPv4 != "" {
+			span.Tags = append(span.Tags, jaeger.String("peer.ipv4", remote.IPv4))
+		}
+		if remote.IPv6 != "" {
+			span.Tags = append(span.Tags, jaeger.String("peer.ipv6", remote.IPv6))
+		}
+		if remote.Port != 0 {
+			span.Tags = append(span.Tags, jaeger.String("peer.port", strconv.Itoa(remote.Port)))
+		}
+	}
+
+	for _, annotation := range z.Annotations {
+		span.Logs = append(span.Logs, jaeger.Log{
+			Timestamp: time.UnixMicro(annotation.Timestamp).UTC(),
+			Fields:    []jaeger.KeyValue{jaeger.String("event", annotation.Value)},
+		})
+	}
+
+	return span, nil
+}
+
+// ConvertZipkinSpan converts a single Zipkin v2 span to OTLP using the
+// converter's settings. Zipkin spans carry their service in localEndpoint
+// rather than a process reference, so Config.ProcessMap does not apply. It
+// returns nil if the span's IDs cannot be parsed, are zero, or it is
+// otherwise rejected by the converter settings.
+func (c *Converter) ConvertZipkinSpan(z *ZipkinSpan) *OTLPSpan {
+	return c.convertZipkinToOTLP(z)
+}
+
+// convertZipkinToOTLP is convertJaegerToOTLP for Zipkin spans
+func (c *Converter) convertZipkinToOTLP(z *ZipkinSpan) *OTLPSpan {
+	span, err := zipkinToJaeger(z)
+	if err != nil {
+		c.malformedIDs.Add(1)
+		return nil
+	}
+	return c.parseSpan(span, "", true)
+}
+
+// zipkinTime converts a Zipkin timestamp in microseconds. A missing (zero)
+// timestamp becomes the zero time, so it is counted as invalid and handled
+// like a Jaeger span without a start time rather than starting at the epoch.
+func zipkinTime(micros int64) time.Time {
+	if micros == 0 {
+		return time.Time{}
+	}
+	return time.UnixMicro(micros).UTC()
+}
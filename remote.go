@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"otlp-converter-go/pkg/otlpconvert"
+)
+
+// inputRetryBackoff is the delay before the first retry of a failed input
+// request; it doubles on each further attempt
+const inputRetryBackoff = 500 * time.Millisecond
+
+// inputClient fetches -input URLs. There is no overall timeout since an
+// export can take long to stream; only waiting for the response headers is
+// bounded. Compression is disabled so byte offsets match the object for Range
+// requests; gzip objects are unpacked by sniffing like local files.
+var inputClient = func() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = 60 * time.Second
+	transport.DisableCompression = true
+	return &http.Client{Transport: transport}
+}()
+
+// errInputDenied marks responses that retrying cannot fix
+var errInputDenied = errors.New("request refused")
+
+// httpInput streams an -input URL. If the connection drops mid-body it asks
+// for the rest with a Range request from the last byte read, so a long
+// download does not start over; -input-retries bounds the attempts of each
+// request.
+type httpInput struct {
+	url     string
+	retries int
+	s3      *otlpconvert.S3Client // signs requests for s3:// input; nil otherwise
+	body    io.ReadCloser
+	offset  int64 // bytes read so far
+}
+
+// openURL starts streaming rawURL, retrying transient failures. s3:// URLs
+// are fetched from the object's HTTPS URL with requests signed by
+// credentials from the environment.
+func openURL(rawURL string, retries int) (*httpInput, error) {
+	in := &httpInput{url: rawURL, retries: retries}
+	if otlpconvert.IsS3URL(rawURL) {
+		bucket, key, err := otlpconvert.ParseS3URL(rawURL)
+		if err != nil {
+			return nil, err
+		}
+		if in.s3, err = otlpconvert.NewS3ClientFromEnv(); err != nil {
+			return nil, err
+		}
+		in.url = in.s3.ObjectURL(bucket, key)
+	}
+	if err := in.connect(); err != nil {
+		return nil, err
+	}
+	return in, nil
+}
+
+func (in *httpInput) Read(p []byte) (int, error) {
+	for {
+		n, err := in.body.Read(p)
+		in.offset += int64(n)
+		if err == nil || err == io.EOF {
+			return n, err
+		}
+		if n > 0 {
+			// Hand over what arrived; the error comes back on the next Read
+			return n, nil
+		}
+		slog.Warn("input connection lost, resuming", "url", redactURL(in.url), "offset", in.offset, "error", err)
+		in.body.Close()
+		if err := in.connect(); err != nil {
+			return 0, err
+		}
+	}
+}
+
+func (in *httpInput) Close() error {
+	return in.body.Close()
+}
+
+// connect requests the object from in.offset on, retrying network errors,
+// 429 and 5xx responses with exponential backoff
+func (in *httpInput) connect() error {
+	backoff := inputRetryBackoff
+	for attempt := 0; ; attempt++ {
+		body, err := in.get()
+		if err == nil {
+			in.body = body
+			return nil
+		}
+		if errors.Is(err, errInputDenied) || attempt >= in.retries {
+			return err
+		}
+		slog.Warn("input request failed, retrying", "url", redactURL(in.url), "attempt", attempt+1, "backoff", backoff.String(), "error", err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// get sends one request and returns the body of a successful response
+func (in *httpInput) get() (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, in.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInputDenied, err)
+	}
+	if in.offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(in.offset, 10)+"-")
+	}
+	// Signed per attempt, as the signature covers the time and range
+	if in.s3 != nil {
+		in.s3.SignRequest(req, "")
+	}
+	resp, err := inputClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusOK && in.offset == 0,
+		resp.StatusCode == http.StatusPartialContent && in.offset > 0:
+		return resp.Body, nil
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return nil, fmt.Errorf("%w: server does not support Range requests, cannot resume at byte %d", errInputDenied, in.offset)
+	case (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden) && in.s3 != nil:
+		return nil, fmt.Errorf("%w: HTTP %d, access denied (check the AWS credentials and that they may read the object)", errInputDenied, resp.StatusCode)
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, fmt.Errorf("%w: HTTP %d, access denied (check the credentials in the URL or that a presigned URL has not expired)", errInputDenied, resp.StatusCode)
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	default:
+		return nil, fmt.Errorf("%w: HTTP %d", errInputDenied, resp.StatusCode)
+	}
+}
+
+// redactURL drops the user info and query of a URL for logging, since
+// presigned URLs carry their signature in the query
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	u.User = nil
+	u.RawQuery = ""
+	return u.String()
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"otlp-converter-go/pkg/otlpconvert"
+)
+
+// statsBarWidth is the width of the longest bar in the -stats histograms
+const statsBarWidth = 40
+
+// runStats scans the input with the normal reader and worker count, counting
+// spans per service, span kind and error status, and prints a summary. No
+// OTLP spans are built and no output is written.
+func runStats(config *otlpconvert.Config, inputFiles []string) {
+	converter := otlpconvert.New(*config)
+	stats := otlpconvert.NewExportStats()
+	entryChan := make(chan otlpconvert.BadgerEntry, config.EntryQueue)
+
+	startTime := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < config.NumWorkers; i++ {
+		wg.Add(1)
+		go converter.StatsWorker(entryChan, stats, &wg)
+	}
+	processed := readInputs(inputFiles, entryChan, config, nil, nil)
+	close(entryChan)
+	wg.Wait()
+	elapsed := time.Since(startTime)
+
+	erroredPct := 0.0
+	if stats.Spans > 0 {
+		erroredPct = float64(stats.Errored) / float64(stats.Spans) * 100
+	}
+
+	if config.LogFormat != "text" {
+		slog.Info("export stats",
+			"entries", processed,
+			"spans", stats.Spans,
+			"parse_errors", stats.ParseErrors,
+			"errored", stats.Errored,
+			"errored_pct", fmt.Sprintf("%.1f", erroredPct),
+			"earliest", formatStatsTime(stats.Earliest),
+			"latest", formatStatsTime(stats.Latest),
+			"services", stats.Services,
+			"kinds", stats.Kinds,
+			"elapsed", elapsed.Round(time.Millisecond).String(),
+		)
+		return
+	}
+
+	fmt.Printf("Entries: %d (parse errors: %d)\n", processed, stats.ParseErrors)
+	fmt.Printf("Spans: %d, errored: %d (%.1f%%)\n", stats.Spans, stats.Errored, erroredPct)
+	fmt.Printf("Time range: %s to %s\n", formatStatsTime(stats.Earliest), formatStatsTime(stats.Latest))
+	fmt.Println()
+	fmt.Println("Spans per service:")
+	printHistogram(os.Stdout, stats.Services, stats.Spans, 0)
+	fmt.Println()
+	fmt.Println("Span kinds:")
+	printHistogram(os.Stdout, stats.Kinds, stats.Spans, 0)
+	fmt.Println()
+	fmt.Printf("Scanned in %.1fs\n", elapsed.Seconds())
+}
+
+// printHistogram prints counts largest first, with their share of total and
+// a bar scaled to the largest count. With a positive limit only that many of
+// the largest are printed, followed by a line totalling the rest.
+func printHistogram(w io.Writer, counts map[string]int64, total int64, limit int) {
+	names := make([]string, 0, len(counts))
+	width := 0
+	var largest int64
+	for name, n := range counts {
+		names = append(names, name)
+		width = max(width, len(name))
+		largest = max(largest, n)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	var rest []string
+	if limit > 0 && len(names) > limit {
+		names, rest = names[:limit], names[limit:]
+	}
+	for _, name := range names {
+		n := counts[name]
+		bar := strings.Repeat("█", max(1, int(n*statsBarWidth/largest)))
+		fmt.Fprintf(w, "  %-*s %10d %5.1f%% %s\n", width, name, n, float64(n)/float64(total)*100, bar)
+	}
+	if len(rest) > 0 {
+		var n int64
+		for _, name := range rest {
+			n += counts[name]
+		}
+		label := fmt.Sprintf("(%d more)", len(rest))
+		fmt.Fprintf(w, "  %-*s %10d %5.1f%%\n", width, label, n, float64(n)/float64(total)*100)
+	}
+}
+
+// formatStatsTime formats a span time for the -stats summary, or "-" when no
+// span was seen
+func formatStatsTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}